@@ -0,0 +1,288 @@
+// db/admin/admin.go
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	db "your/path/to/db"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"unix": func(ts int64) string {
+		return time.Unix(ts, 0).UTC().Format("2006-01-02 15:04:05")
+	},
+}).ParseFS(templateFS, "templates/*.html"))
+
+// pageSize is the number of records per list page
+const pageSize = 25
+
+// Handler returns an HTTP handler serving a small admin UI for the records
+// table. Mount it with http.StripPrefix:
+//
+//	http.Handle("/admin/", http.StripPrefix("/admin", admin.Handler(database)))
+//
+// It has no authentication of its own; put it behind whatever protects
+// your internal tools.
+func Handler(database *sql.DB) http.Handler {
+	h := &handler{db: database}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.list)
+	mux.HandleFunc("/edit", h.edit)
+	mux.HandleFunc("/export.csv", h.export)
+	return mux
+}
+
+type handler struct {
+	db *sql.DB
+}
+
+type listPage struct {
+	Records  []*db.Record
+	Query    string
+	Page     int
+	PrevPage int
+	NextPage int
+	Total    int64
+}
+
+// list shows a page of records, optionally filtered by a search term
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	opts := db.QueryOptions{Limit: pageSize + 1, Offset: (page - 1) * pageSize}
+
+	var records []*db.Record
+	var err error
+	if query != "" {
+		records, err = db.SearchRecords(h.db, query, opts)
+	} else {
+		records, err = db.GetRecords(h.db, opts)
+	}
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	data := listPage{Query: query, Page: page, PrevPage: page - 1}
+	// One extra row tells us whether there is a next page
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		data.NextPage = page + 1
+	}
+	data.Records = records
+	if query != "" {
+		data.Total, err = db.CountSearchRecords(h.db, query)
+	} else {
+		data.Total, err = db.GetRecordCount(h.db)
+	}
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	h.render(w, "list.html", data)
+}
+
+type editPage struct {
+	Record *db.Record
+	// Active is the is_active option to select: "", "true" or "false"
+	Active string
+	CSRF   string
+	Error  string
+	Saved  bool
+}
+
+// edit shows and saves the edit form for a single record
+func (h *handler) edit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	record, err := db.GetRecord(h.db, id)
+	if err == sql.ErrNoRows {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.fail(w, err)
+		return
+	}
+
+	token := csrfToken(w, r)
+	data := editPage{Record: record, CSRF: token}
+	if r.Method == http.MethodPost {
+		if !validCSRF(r) {
+			http.Error(w, "invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		if err := applyForm(r, record); err != nil {
+			data.Error = err.Error()
+		} else if err := db.UpdateRecord(h.db, record); err != nil {
+			data.Error = err.Error()
+		} else {
+			data.Saved = true
+		}
+	}
+	if record.IsActive != nil {
+		data.Active = strconv.FormatBool(*record.IsActive)
+	}
+
+	h.render(w, "edit.html", data)
+}
+
+// csrfCookie holds the token the edit form must echo back
+const csrfCookie = "admin_csrf"
+
+// csrfToken returns the request's CSRF token, issuing a new cookie when
+// there is none yet
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(csrfCookie); err == nil && c.Value != "" {
+		return c.Value
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("admin: generating CSRF token: %v", err))
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+	return token
+}
+
+// validCSRF reports whether the submitted form carries the same token as
+// the cookie. A cross-site form can't read the cookie, so it can't match.
+func validCSRF(r *http.Request) bool {
+	c, err := r.Cookie(csrfCookie)
+	if err != nil || c.Value == "" {
+		return false
+	}
+	token := r.PostFormValue("csrf_token")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.Value)) == 1
+}
+
+// applyForm copies the submitted form fields onto record
+func applyForm(r *http.Request, record *db.Record) error {
+	name := strings.TrimSpace(r.PostFormValue("name"))
+	if name == "" {
+		return errors.New("name is required")
+	}
+	record.Name = name
+
+	record.Description = nil
+	if d := r.PostFormValue("description"); d != "" {
+		record.Description = &d
+	}
+
+	record.Amount = nil
+	if a := strings.TrimSpace(r.PostFormValue("amount")); a != "" {
+		amount, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return errors.New("amount must be a number")
+		}
+		record.Amount = &amount
+	}
+
+	record.IsActive = nil
+	if a := r.PostFormValue("is_active"); a != "" {
+		active := a == "true"
+		record.IsActive = &active
+	}
+
+	now := time.Now().Unix()
+	record.UpdatedAtUnix = &now
+	return nil
+}
+
+// export streams every record as CSV, in the format ApplyCSVUpdates reads
+func (h *handler) export(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="records.csv"`)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "name", "description", "amount", "is_active", "created_at", "updated_at"})
+
+	for offset := 0; ; offset += 500 {
+		records, err := db.GetRecords(h.db, db.QueryOptions{
+			Limit: 500, Offset: offset, SortBy: "id", Order: "ASC",
+		})
+		if err != nil {
+			// Headers are already sent, so all we can do is stop and log
+			log.Printf("admin: export failed at offset %d: %v", offset, err)
+			break
+		}
+		for _, rec := range records {
+			out.Write(recordRow(rec))
+		}
+		if len(records) < 500 {
+			break
+		}
+	}
+	out.Flush()
+}
+
+// recordRow formats a record as a CSV row; NULLs become empty cells
+func recordRow(rec *db.Record) []string {
+	row := []string{
+		strconv.FormatInt(rec.ID, 10),
+		rec.Name,
+		"", "", "",
+		strconv.FormatInt(rec.CreatedAtUnix, 10),
+		"",
+	}
+	if rec.Description != nil {
+		row[2] = *rec.Description
+	}
+	if rec.Amount != nil {
+		row[3] = strconv.FormatFloat(*rec.Amount, 'f', -1, 64)
+	}
+	if rec.IsActive != nil {
+		row[4] = strconv.FormatBool(*rec.IsActive)
+	}
+	if rec.UpdatedAtUnix != nil {
+		row[6] = strconv.FormatInt(*rec.UpdatedAtUnix, 10)
+	}
+	return row
+}
+
+func (h *handler) render(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("admin: rendering %s: %v", name, err)
+	}
+}
+
+func (h *handler) fail(w http.ResponseWriter, err error) {
+	log.Printf("admin: %v", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
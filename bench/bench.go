@@ -0,0 +1,264 @@
+// db/bench/bench.go
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	db "your/path/to/db"
+)
+
+// Operation names used in reports
+const (
+	OpInsert = "insert"
+	OpGet    = "get"
+	OpList   = "list"
+	OpSearch = "search"
+	OpUpdate = "update"
+)
+
+// Config describes a workload against the records table
+type Config struct {
+	Duration    time.Duration // defaults to 10s
+	Concurrency int           // parallel workers, defaults to 8
+	// Seed is the number of records inserted before measuring starts,
+	// so reads have something to hit; defaults to 1000
+	Seed int
+	// Mix weights each operation; defaults to a read-heavy mix
+	Mix map[string]int
+}
+
+// DefaultMix is a read-heavy workload
+var DefaultMix = map[string]int{
+	OpGet:    60,
+	OpList:   15,
+	OpSearch: 5,
+	OpInsert: 10,
+	OpUpdate: 10,
+}
+
+// OpStats summarizes one operation type
+type OpStats struct {
+	Count      int
+	Errors     int
+	Throughput float64 // operations per second
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+// Report is the result of a benchmark run
+type Report struct {
+	Elapsed time.Duration
+	Ops     map[string]*OpStats
+}
+
+// Run seeds the table, then runs the workload until the duration
+// elapses or ctx is cancelled. It inserts rows; point it at a scratch
+// database.
+func Run(ctx context.Context, database *sql.DB, cfg Config) (*Report, error) {
+	if cfg.Duration <= 0 {
+		cfg.Duration = 10 * time.Second
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 8
+	}
+	if cfg.Seed <= 0 {
+		cfg.Seed = 1000
+	}
+	if len(cfg.Mix) == 0 {
+		cfg.Mix = DefaultMix
+	}
+
+	ops, weights, total := mixTable(cfg.Mix)
+	if total == 0 {
+		return nil, fmt.Errorf("workload mix has no positive weights")
+	}
+
+	var maxID int64
+	for i := 0; i < cfg.Seed; i++ {
+		record := newRecord(rand.Int63())
+		if err := db.InsertRecord(database, record); err != nil {
+			return nil, fmt.Errorf("seeding: %v", err)
+		}
+		atomic.StoreInt64(&maxID, record.ID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	samples := make([]map[string][]time.Duration, cfg.Concurrency)
+	errs := make([]map[string]int, cfg.Concurrency)
+	start := time.Now()
+
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency; w++ {
+		samples[w] = make(map[string][]time.Duration)
+		errs[w] = make(map[string]int)
+
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+
+			for ctx.Err() == nil {
+				op := pick(rng, ops, weights, total)
+				opStart := time.Now()
+				err := runOp(database, op, rng, &maxID)
+				samples[w][op] = append(samples[w][op], time.Since(opStart))
+				if err != nil && err != sql.ErrNoRows {
+					errs[w][op]++
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	return buildReport(time.Since(start), samples, errs), nil
+}
+
+// runOp executes a single operation
+func runOp(database *sql.DB, op string, rng *rand.Rand, maxID *int64) error {
+	randomID := func() int64 { return rng.Int63n(atomic.LoadInt64(maxID)) + 1 }
+
+	switch op {
+	case OpInsert:
+		record := newRecord(rng.Int63())
+		if err := db.InsertRecord(database, record); err != nil {
+			return err
+		}
+		for {
+			current := atomic.LoadInt64(maxID)
+			if record.ID <= current || atomic.CompareAndSwapInt64(maxID, current, record.ID) {
+				return nil
+			}
+		}
+	case OpGet:
+		_, err := db.GetRecord(database, randomID())
+		return err
+	case OpList:
+		_, err := db.GetRecords(database, db.QueryOptions{Limit: 20, Offset: rng.Intn(100)})
+		return err
+	case OpSearch:
+		_, err := db.SearchRecords(database, fmt.Sprintf("bench-%d", rng.Intn(100)), db.QueryOptions{Limit: 20})
+		return err
+	case OpUpdate:
+		record := newRecord(rng.Int63())
+		record.ID = randomID()
+		now := time.Now().Unix()
+		record.UpdatedAtUnix = &now
+		return db.UpdateRecord(database, record)
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// newRecord builds a synthetic record
+func newRecord(n int64) *db.Record {
+	amount := float64(n%100000) / 100
+	active := n%2 == 0
+	description := fmt.Sprintf("synthetic benchmark record %d", n)
+	return &db.Record{
+		Name:          fmt.Sprintf("bench-%d", n%1000),
+		Description:   &description,
+		Amount:        &amount,
+		IsActive:      &active,
+		CreatedAtUnix: time.Now().Unix(),
+	}
+}
+
+// mixTable flattens the mix into parallel slices in a stable order
+func mixTable(mix map[string]int) ([]string, []int, int) {
+	var ops []string
+	for op, weight := range mix {
+		if weight > 0 {
+			ops = append(ops, op)
+		}
+	}
+	sort.Strings(ops)
+
+	weights := make([]int, len(ops))
+	total := 0
+	for i, op := range ops {
+		weights[i] = mix[op]
+		total += mix[op]
+	}
+	return ops, weights, total
+}
+
+// pick chooses an operation according to the weights
+func pick(rng *rand.Rand, ops []string, weights []int, total int) string {
+	n := rng.Intn(total)
+	for i, w := range weights {
+		if n < w {
+			return ops[i]
+		}
+		n -= w
+	}
+	return ops[len(ops)-1]
+}
+
+// buildReport merges per-worker samples into percentiles
+func buildReport(elapsed time.Duration, samples []map[string][]time.Duration, errs []map[string]int) *Report {
+	merged := make(map[string][]time.Duration)
+	errCounts := make(map[string]int)
+	for w := range samples {
+		for op, s := range samples[w] {
+			merged[op] = append(merged[op], s...)
+		}
+		for op, n := range errs[w] {
+			errCounts[op] += n
+		}
+	}
+
+	report := &Report{Elapsed: elapsed, Ops: make(map[string]*OpStats)}
+	for op, s := range merged {
+		sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+		report.Ops[op] = &OpStats{
+			Count:      len(s),
+			Errors:     errCounts[op],
+			Throughput: float64(len(s)) / elapsed.Seconds(),
+			P50:        percentile(s, 0.50),
+			P95:        percentile(s, 0.95),
+			P99:        percentile(s, 0.99),
+			Max:        s[len(s)-1],
+		}
+	}
+	return report
+}
+
+// percentile returns the p-th percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+// Print writes the report as a table
+func (r *Report) Print(w io.Writer) {
+	var ops []string
+	for op := range r.Ops {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	fmt.Fprintf(w, "elapsed: %v\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "%-8s %8s %6s %10s %10s %10s %10s %10s\n",
+		"op", "count", "errors", "ops/s", "p50", "p95", "p99", "max")
+	for _, op := range ops {
+		s := r.Ops[op]
+		fmt.Fprintf(w, "%-8s %8d %6d %10.1f %10v %10v %10v %10v\n",
+			op, s.Count, s.Errors, s.Throughput,
+			s.P50.Round(time.Microsecond), s.P95.Round(time.Microsecond),
+			s.P99.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+}
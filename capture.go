@@ -0,0 +1,149 @@
+// db/capture.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+// Statement is a captured SQL statement and its arguments
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// String renders the statement on a single line for logs and diffs
+func (s Statement) String() string {
+	query := normalizeQuery(s.Query)
+	if len(s.Args) == 0 {
+		return query
+	}
+	return fmt.Sprintf("%s %v", query, s.Args)
+}
+
+// captureHandler decides what a captured statement returns
+type captureHandler interface {
+	exec(stmt Statement) (driver.Result, error)
+	query(stmt Statement) (driver.Rows, error)
+}
+
+// openCaptureDB returns a *sql.DB whose statements go to h instead of a
+// database, so every helper in this package can run against it unchanged
+func openCaptureDB(h captureHandler) *sql.DB {
+	return sql.OpenDB(captureConnector{h: h})
+}
+
+type captureConnector struct {
+	h captureHandler
+}
+
+func (c captureConnector) Connect(context.Context) (driver.Conn, error) {
+	return &captureConn{h: c.h}, nil
+}
+
+func (c captureConnector) Driver() driver.Driver {
+	return captureDriver{}
+}
+
+type captureDriver struct{}
+
+func (captureDriver) Open(string) (driver.Conn, error) {
+	return nil, fmt.Errorf("capture driver cannot be opened by name")
+}
+
+type captureConn struct {
+	h captureHandler
+}
+
+func (c *captureConn) Prepare(query string) (driver.Stmt, error) {
+	return &captureStmt{conn: c, query: query}, nil
+}
+
+func (c *captureConn) Close() error { return nil }
+
+func (c *captureConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *captureConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.h.exec(Statement{Query: "BEGIN"}); err != nil {
+		return nil, err
+	}
+	return captureTx{h: c.h}, nil
+}
+
+func (c *captureConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.h.exec(Statement{Query: query, Args: namedArgs(args)})
+}
+
+func (c *captureConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.h.query(Statement{Query: query, Args: namedArgs(args)})
+}
+
+type captureTx struct {
+	h captureHandler
+}
+
+func (t captureTx) Commit() error {
+	_, err := t.h.exec(Statement{Query: "COMMIT"})
+	return err
+}
+
+func (t captureTx) Rollback() error {
+	_, err := t.h.exec(Statement{Query: "ROLLBACK"})
+	return err
+}
+
+type captureStmt struct {
+	conn  *captureConn
+	query string
+}
+
+func (s *captureStmt) Close() error  { return nil }
+func (s *captureStmt) NumInput() int { return -1 }
+
+func (s *captureStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.h.exec(Statement{Query: s.query, Args: valueArgs(args)})
+}
+
+func (s *captureStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.h.query(Statement{Query: s.query, Args: valueArgs(args)})
+}
+
+func namedArgs(args []driver.NamedValue) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = arg.Value
+	}
+	return out
+}
+
+func valueArgs(args []driver.Value) []interface{} {
+	out := make([]interface{}, len(args))
+	for i, arg := range args {
+		out[i] = arg
+	}
+	return out
+}
+
+// cannedRows serves a fixed set of rows to database/sql
+type cannedRows struct {
+	columns []string
+	values  [][]driver.Value
+	pos     int
+}
+
+func (r *cannedRows) Columns() []string { return r.columns }
+func (r *cannedRows) Close() error      { return nil }
+
+func (r *cannedRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.pos])
+	r.pos++
+	return nil
+}
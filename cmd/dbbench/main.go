@@ -0,0 +1,73 @@
+// db/cmd/dbbench/main.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	db "your/path/to/db"
+	"your/path/to/db/bench"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL connection string (use a scratch database)")
+	duration := flag.Duration("duration", 10*time.Second, "how long to run the workload")
+	concurrency := flag.Int("concurrency", 8, "parallel workers")
+	seed := flag.Int("seed", 1000, "records to insert before measuring")
+	mix := flag.String("mix", "", "operation weights, e.g. get=60,list=15,search=5,insert=10,update=10")
+	flag.Parse()
+
+	database, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+	database.SetMaxOpenConns(*concurrency)
+
+	if err := db.CreateTable(database); err != nil {
+		log.Fatal(err)
+	}
+
+	cfg := bench.Config{Duration: *duration, Concurrency: *concurrency, Seed: *seed}
+	if *mix != "" {
+		if cfg.Mix, err = parseMix(*mix); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	report, err := bench.Run(ctx, database, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	report.Print(os.Stdout)
+}
+
+// parseMix reads "op=weight,op=weight"
+func parseMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	for _, part := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid mix entry %q, want op=weight", part)
+		}
+		weight, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		mix[kv[0]] = weight
+	}
+	return mix, nil
+}
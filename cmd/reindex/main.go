@@ -0,0 +1,51 @@
+// db/cmd/reindex/main.go
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+
+	_ "github.com/lib/pq"
+
+	"your/path/to/db/search"
+)
+
+func main() {
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "PostgreSQL connection string")
+	url := flag.String("url", "http://localhost:9200", "OpenSearch/Elasticsearch URL")
+	index := flag.String("index", "records", "index name")
+	batch := flag.Int("batch", 500, "records per bulk request")
+	follow := flag.Bool("follow", false, "keep the index in sync after reindexing")
+	flag.Parse()
+
+	database, err := sql.Open("postgres", *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	indexer := &search.OpenSearchIndexer{URL: *url, IndexName: *index}
+
+	if *follow {
+		// The syncer starts listening before it reindexes, so nothing
+		// committed in between is missed
+		syncer := &search.Syncer{DB: database, Indexer: indexer, ConnStr: *dsn, BatchSize: *batch}
+		if err := syncer.Run(ctx); err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	n, err := search.Reindex(ctx, database, indexer, *batch)
+	if err != nil {
+		log.Fatalf("reindex failed after %d records: %v", n, err)
+	}
+	log.Printf("reindexed %d records into %s", n, *index)
+}
@@ -0,0 +1,101 @@
+// db/counters/counters.go
+package counters
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLike makes s match only itself in a LIKE pattern
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+// CreateTable creates the counters table
+func CreateTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS counters (
+		key VARCHAR(255) PRIMARY KEY,
+		value BIGINT NOT NULL DEFAULT 0,
+		updated_at BIGINT NOT NULL     -- Unix timestamp
+	);
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// IncrementCounter atomically adds delta to the counter, creating it if
+// needed, and returns the new value. Use a negative delta to decrement.
+func IncrementCounter(db *sql.DB, key string, delta int64) (int64, error) {
+	query := `
+	INSERT INTO counters (key, value, updated_at)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (key) DO UPDATE SET
+		value = counters.value + EXCLUDED.value,
+		updated_at = EXCLUDED.updated_at
+	RETURNING value`
+
+	var value int64
+	err := db.QueryRow(query, key, delta, time.Now().Unix()).Scan(&value)
+	return value, err
+}
+
+// GetCounter returns the current value of a counter, or 0 if it doesn't exist
+func GetCounter(db *sql.DB, key string) (int64, error) {
+	var value int64
+	err := db.QueryRow("SELECT value FROM counters WHERE key = $1", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return value, err
+}
+
+// ResetCounter sets a counter back to zero and returns its previous value
+func ResetCounter(db *sql.DB, key string) (int64, error) {
+	query := `
+	UPDATE counters c SET value = 0, updated_at = $2
+	FROM (SELECT value FROM counters WHERE key = $1 FOR UPDATE) old
+	WHERE c.key = $1
+	RETURNING old.value`
+
+	var previous int64
+	err := db.QueryRow(query, key, time.Now().Unix()).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return previous, err
+}
+
+// DeleteCounter removes a counter entirely
+func DeleteCounter(db *sql.DB, key string) error {
+	_, err := db.Exec("DELETE FROM counters WHERE key = $1", key)
+	return err
+}
+
+// ListCounters returns all counters whose key starts with prefix
+func ListCounters(db *sql.DB, prefix string) (map[string]int64, error) {
+	rows, err := db.Query(
+		`SELECT key, value FROM counters WHERE key LIKE $1 ESCAPE '\' ORDER BY key`,
+		escapeLike(prefix)+"%",
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counters := make(map[string]int64)
+	for rows.Next() {
+		var key string
+		var value int64
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		counters[key] = value
+	}
+
+	return counters, rows.Err()
+}
@@ -0,0 +1,207 @@
+// db/csvupdate.go
+package db
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSV row outcomes reported by ApplyCSVUpdates
+const (
+	CSVRowUpdated  = "updated"
+	CSVRowNotFound = "not_found"
+	CSVRowInvalid  = "invalid"
+	CSVRowFailed   = "failed"
+)
+
+// CSVRowResult is the outcome of a single CSV row
+type CSVRowResult struct {
+	Line   int
+	ID     int64
+	Status string
+	Err    error
+}
+
+// CSVUpdateReport summarizes an ApplyCSVUpdates run
+type CSVUpdateReport struct {
+	Columns   []string
+	Rows      []CSVRowResult
+	Updated   int
+	Failed    int
+	Committed bool
+}
+
+// csvColumnParsers converts a CSV cell into a value for each updatable
+// column. Empty cells become NULL for nullable columns.
+var csvColumnParsers = map[string]func(string) (interface{}, error){
+	"name": func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, fmt.Errorf("name cannot be empty")
+		}
+		return s, nil
+	},
+	"description": func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return s, nil
+	},
+	"amount": func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return strconv.ParseFloat(s, 64)
+	},
+	"is_active": func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return strconv.ParseBool(s)
+	},
+	"created_at": func(s string) (interface{}, error) {
+		return strconv.ParseInt(s, 10, 64)
+	},
+	"updated_at": func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return strconv.ParseInt(s, 10, 64)
+	},
+}
+
+// ApplyCSVUpdates updates records from a CSV file whose header names an
+// id column plus any of the updatable columns. Only the columns present in
+// the CSV are changed. All rows are applied in one transaction, which is
+// committed only if every row succeeds; the report lists each row's outcome
+// either way.
+func ApplyCSVUpdates(db *sql.DB, r io.Reader) (*CSVUpdateReport, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	idIndex := -1
+	var columns []string
+	var columnIndexes []int
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "id" {
+			idIndex = i
+			continue
+		}
+		if _, ok := csvColumnParsers[name]; !ok {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns = append(columns, name)
+		columnIndexes = append(columnIndexes, i)
+	}
+	if idIndex < 0 {
+		return nil, fmt.Errorf("CSV header has no id column")
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("CSV header has no columns to update")
+	}
+
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		sets[i] = fmt.Sprintf("%s = $%d", column, i+1)
+	}
+	query := fmt.Sprintf("UPDATE records SET %s WHERE id = $%d",
+		strings.Join(sets, ", "), len(columns)+1)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	report := &CSVUpdateReport{Columns: columns}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("failed to read CSV line %d: %v", line, err)
+		}
+
+		result := applyCSVRow(stmt, row, line, idIndex, columns, columnIndexes)
+		if result.Status == CSVRowUpdated {
+			report.Updated++
+		} else {
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, result)
+
+		// A failed statement aborts the transaction, so stop here
+		if result.Status == CSVRowFailed {
+			break
+		}
+	}
+
+	if report.Failed > 0 {
+		return report, fmt.Errorf("%d of %d CSV rows failed, no changes applied",
+			report.Failed, len(report.Rows))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// applyCSVRow parses and applies a single CSV row
+func applyCSVRow(stmt *sql.Stmt, row []string, line, idIndex int, columns []string, columnIndexes []int) CSVRowResult {
+	result := CSVRowResult{Line: line}
+
+	id, err := strconv.ParseInt(strings.TrimSpace(row[idIndex]), 10, 64)
+	if err != nil {
+		result.Status, result.Err = CSVRowInvalid, fmt.Errorf("invalid id: %v", err)
+		return result
+	}
+	result.ID = id
+
+	args := make([]interface{}, 0, len(columns)+1)
+	for i, column := range columns {
+		value, err := csvColumnParsers[column](strings.TrimSpace(row[columnIndexes[i]]))
+		if err != nil {
+			result.Status, result.Err = CSVRowInvalid, fmt.Errorf("invalid %s: %v", column, err)
+			return result
+		}
+		args = append(args, value)
+	}
+	args = append(args, id)
+
+	res, err := stmt.Exec(args...)
+	if err != nil {
+		result.Status, result.Err = CSVRowFailed, err
+		return result
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		result.Status, result.Err = CSVRowFailed, err
+		return result
+	}
+	if rowsAffected == 0 {
+		result.Status, result.Err = CSVRowNotFound, fmt.Errorf("record with ID %d not found", id)
+		return result
+	}
+
+	result.Status = CSVRowUpdated
+	return result
+}
@@ -0,0 +1,64 @@
+// db/dryrun.go
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+)
+
+// DryRun captures the statements helpers would execute without touching a
+// database. Pass DryRun.DB to any helper, then inspect Statements:
+//
+//	dry := db.NewDryRun()
+//	db.TruncateTable(dry.DB, db.TruncateOptions{Confirm: "records"})
+//	for _, stmt := range dry.Statements() {
+//		fmt.Println(stmt)
+//	}
+//
+// Exec calls report one affected row so not-found checks pass. Queries
+// return no rows, so helpers that read results (e.g. InsertRecord's
+// RETURNING id) get sql.ErrNoRows after their statement is captured.
+type DryRun struct {
+	*sql.DB
+
+	mu         sync.Mutex
+	statements []Statement
+}
+
+// NewDryRun creates a dry-run database handle
+func NewDryRun() *DryRun {
+	d := &DryRun{}
+	d.DB = openCaptureDB(d)
+	return d
+}
+
+// Statements returns the statements captured so far, in execution order
+func (d *DryRun) Statements() []Statement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Statement(nil), d.statements...)
+}
+
+// Reset discards the captured statements
+func (d *DryRun) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = nil
+}
+
+func (d *DryRun) capture(stmt Statement) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, stmt)
+}
+
+func (d *DryRun) exec(stmt Statement) (driver.Result, error) {
+	d.capture(stmt)
+	return driver.RowsAffected(1), nil
+}
+
+func (d *DryRun) query(stmt Statement) (driver.Rows, error) {
+	d.capture(stmt)
+	return &cannedRows{}, nil
+}
@@ -0,0 +1,155 @@
+// db/duplicates.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// duplicateColumns are the columns FindDuplicates can group by
+var duplicateColumns = map[string]bool{
+	"name":        true,
+	"description": true,
+	"amount":      true,
+	"is_active":   true,
+}
+
+// DuplicateGroup is a set of records sharing the same values
+type DuplicateGroup struct {
+	// Key holds the shared value of each grouping column; nil means NULL
+	Key map[string]*string
+	// IDs are the record IDs in the group, oldest first
+	IDs []int64
+}
+
+// FindDuplicates groups records that have identical values in the given
+// columns (name by default) and returns every group with more than one record
+func FindDuplicates(db *sql.DB, by ...string) ([]*DuplicateGroup, error) {
+	if len(by) == 0 {
+		by = []string{"name"}
+	}
+
+	keys := make([]string, len(by))
+	for i, column := range by {
+		if !duplicateColumns[column] {
+			return nil, fmt.Errorf("cannot group duplicates by column %q", column)
+		}
+		keys[i] = column + "::text"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, string_agg(id::text, ',' ORDER BY id)
+		FROM records
+		GROUP BY %s
+		HAVING COUNT(*) > 1
+		ORDER BY MIN(id)
+	`, strings.Join(keys, ", "), strings.Join(by, ", "))
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*DuplicateGroup
+	for rows.Next() {
+		values := make([]sql.NullString, len(by))
+		var ids string
+		dest := make([]interface{}, 0, len(by)+1)
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &ids)
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		group := &DuplicateGroup{Key: make(map[string]*string)}
+		for i, column := range by {
+			if values[i].Valid {
+				v := values[i].String
+				group.Key[column] = &v
+			} else {
+				group.Key[column] = nil
+			}
+		}
+		for _, s := range strings.Split(ids, ",") {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			group.IDs = append(group.IDs, id)
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, rows.Err()
+}
+
+// MergeRecords folds duplicate records into keepID and deletes them.
+// Columns that are NULL on the kept record are filled from the duplicates
+// in ID order. Each duplicate's final state is written to records_audit as
+// a MERGE entry pointing at the kept record, so nothing is lost.
+func MergeRecords(db *sql.DB, keepID int64, duplicateIDs ...int64) error {
+	if err := CreateAuditTable(db); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM records WHERE id = $1)", keepID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("record with ID %d not found", keepID)
+	}
+
+	now := time.Now().Unix()
+	for _, dupID := range duplicateIDs {
+		if dupID == keepID {
+			continue
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO records_audit (record_id, operation, old_data, new_data, changed_at)
+			SELECT r.id, 'MERGE', to_jsonb(r), jsonb_build_object('merged_into', $1::BIGINT), $3
+			FROM records r WHERE r.id = $2`,
+			keepID, dupID, now,
+		)
+		if err != nil {
+			return err
+		}
+		if err := requireAffected(result, dupID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE records k
+			SET description = COALESCE(k.description, d.description),
+				amount = COALESCE(k.amount, d.amount),
+				is_active = COALESCE(k.is_active, d.is_active),
+				updated_at = $3
+			FROM records d
+			WHERE k.id = $1 AND d.id = $2`,
+			keepID, dupID, now,
+		)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("DELETE FROM records WHERE id = $1", dupID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
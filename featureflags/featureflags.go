@@ -0,0 +1,268 @@
+// db/featureflags/featureflags.go
+package featureflags
+
+import (
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the NOTIFY channel used to invalidate caches
+const notifyChannel = "feature_flags"
+
+// Flag is a stored feature flag
+type Flag struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	// Rollout is the percentage (1-100) of subjects a PercentRollout
+	// flag is enabled for. Zero means 100, as the column default does;
+	// turn Enabled off to disable the flag for everyone.
+	Rollout   int   `json:"rollout"`
+	UpdatedAt int64 `json:"updated_at"` // Unix timestamp
+}
+
+// CreateTable creates the feature_flags table
+func CreateTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS feature_flags (
+		name VARCHAR(255) PRIMARY KEY,
+		enabled BOOLEAN NOT NULL DEFAULT FALSE,
+		rollout INTEGER NOT NULL DEFAULT 100 CHECK (rollout BETWEEN 0 AND 100),
+		updated_at BIGINT NOT NULL     -- Unix timestamp
+	);
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// Store reads flags through an in-memory cache. The cache is reloaded
+// when it is older than the TTL or when another process changes a flag
+// (once Listen has been called).
+type Store struct {
+	db  *sql.DB
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	flags    map[string]Flag
+	loadedAt time.Time
+	// gen counts invalidations, so a load that was already running when
+	// one happened doesn't cache what it read
+	gen uint64
+
+	listener  *pq.Listener
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewStore creates a flag store. A ttl of zero defaults to 30 seconds.
+func NewStore(db *sql.DB, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+	return &Store{db: db, ttl: ttl}
+}
+
+// Listen subscribes to flag change notifications on a dedicated
+// connection, so updates from other processes apply immediately instead
+// of after the TTL
+func (s *Store) Listen(connStr string) error {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, nil)
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	s.listener = listener
+	s.done = make(chan struct{})
+	go s.watch()
+	return nil
+}
+
+// watch invalidates the cache on every notification. A nil notification
+// means the connection was re-established and events may have been missed.
+func (s *Store) watch() {
+	for {
+		select {
+		case <-s.listener.Notify:
+			s.Invalidate()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops listening for notifications. Calling it again is a no-op.
+func (s *Store) Close() error {
+	if s.listener == nil {
+		return nil
+	}
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.listener.Close()
+	})
+	return err
+}
+
+// Invalidate forces the next read to reload flags from the database
+func (s *Store) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+	s.gen++
+}
+
+// load returns the cached flags, reloading them if stale
+func (s *Store) load() (map[string]Flag, error) {
+	s.mu.RLock()
+	if s.flags != nil && time.Since(s.loadedAt) < s.ttl {
+		flags := s.flags
+		s.mu.RUnlock()
+		return flags, nil
+	}
+	gen := s.gen
+	s.mu.RUnlock()
+
+	rows, err := s.db.Query("SELECT name, enabled, rollout, updated_at FROM feature_flags")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	flags := make(map[string]Flag)
+	for rows.Next() {
+		var flag Flag
+		if err := rows.Scan(&flag.Name, &flag.Enabled, &flag.Rollout, &flag.UpdatedAt); err != nil {
+			return nil, err
+		}
+		flags[flag.Name] = flag
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	// An Invalidate during the query means the rows may predate the
+	// change, so leave the cache stale for the next read
+	if s.gen == gen {
+		s.flags, s.loadedAt = flags, time.Now()
+	}
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// Get returns a flag by name
+func (s *Store) Get(name string) (Flag, bool, error) {
+	flags, err := s.load()
+	if err != nil {
+		return Flag{}, false, err
+	}
+	flag, ok := flags[name]
+	return flag, ok, nil
+}
+
+// List returns all flags
+func (s *Store) List() ([]Flag, error) {
+	flags, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Flag, 0, len(flags))
+	for _, flag := range flags {
+		list = append(list, flag)
+	}
+	return list, nil
+}
+
+// ErrInvalidRollout is returned by Set for a Rollout outside 0-100
+var ErrInvalidRollout = errors.New("featureflags: rollout must be between 0 and 100")
+
+// Set creates or updates a flag, stamping its UpdatedAt, and notifies
+// other processes. A zero Rollout is stored as 100.
+func (s *Store) Set(flag *Flag) error {
+	if flag.Rollout < 0 || flag.Rollout > 100 {
+		return ErrInvalidRollout
+	}
+	if flag.Rollout == 0 {
+		flag.Rollout = 100
+	}
+	flag.UpdatedAt = time.Now().Unix()
+	query := `
+	INSERT INTO feature_flags (name, enabled, rollout, updated_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (name) DO UPDATE SET
+		enabled = EXCLUDED.enabled,
+		rollout = EXCLUDED.rollout,
+		updated_at = EXCLUDED.updated_at`
+
+	if _, err := s.db.Exec(query, flag.Name, flag.Enabled, flag.Rollout, flag.UpdatedAt); err != nil {
+		return err
+	}
+	return s.notify(flag.Name)
+}
+
+// Delete removes a flag and notifies other processes
+func (s *Store) Delete(name string) error {
+	if _, err := s.db.Exec("DELETE FROM feature_flags WHERE name = $1", name); err != nil {
+		return err
+	}
+	return s.notify(name)
+}
+
+// notify invalidates the local cache and tells listening processes
+func (s *Store) notify(name string) error {
+	s.Invalidate()
+	_, err := s.db.Exec("SELECT pg_notify($1, $2)", notifyChannel, name)
+	return err
+}
+
+// BoolFlag is an on/off flag with a default used when it is missing or
+// can't be read
+type BoolFlag struct {
+	store *Store
+	name  string
+	def   bool
+}
+
+// BoolFlag returns a typed handle for an on/off flag
+func (s *Store) BoolFlag(name string, def bool) *BoolFlag {
+	return &BoolFlag{store: s, name: name, def: def}
+}
+
+// Enabled reports whether the flag is on
+func (f *BoolFlag) Enabled() bool {
+	flag, ok, err := f.store.Get(f.name)
+	if err != nil || !ok {
+		return f.def
+	}
+	return flag.Enabled
+}
+
+// PercentRollout is a flag enabled for a stable percentage of subjects
+// (users, accounts, ...). A missing flag is disabled for everyone.
+type PercentRollout struct {
+	store *Store
+	name  string
+}
+
+// PercentRollout returns a typed handle for a percentage rollout flag
+func (s *Store) PercentRollout(name string) *PercentRollout {
+	return &PercentRollout{store: s, name: name}
+}
+
+// EnabledFor reports whether the flag is on for subject. The same subject
+// always lands in the same bucket, so raising the rollout only adds subjects.
+func (f *PercentRollout) EnabledFor(subject string) bool {
+	flag, ok, err := f.store.Get(f.name)
+	if err != nil || !ok || !flag.Enabled {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(f.name + ":" + subject))
+	return int(h.Sum32()%100) < flag.Rollout
+}
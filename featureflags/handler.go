@@ -0,0 +1,85 @@
+// db/featureflags/handler.go
+package featureflags
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Handler returns an admin HTTP handler for managing flags. Mount it with
+// http.StripPrefix:
+//
+//	GET    /        list all flags
+//	GET    /{name}  get one flag
+//	PUT    /{name}  create or update a flag from a JSON body; a missing
+//	                or zero rollout means 100, as with Store.Set
+//	DELETE /{name}  delete a flag
+func (s *Store) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(r.URL.Path, "/")
+
+		if name == "" {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			flags, err := s.List()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+			writeJSON(w, flags)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			flag, ok, err := s.Get(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			writeJSON(w, flag)
+
+		case http.MethodPut:
+			var flag Flag
+			if err := json.NewDecoder(r.Body).Decode(&flag); err != nil {
+				http.Error(w, "invalid flag: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			flag.Name = name
+			err := s.Set(&flag)
+			if err == ErrInvalidRollout {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, flag)
+
+		case http.MethodDelete:
+			if err := s.Delete(name); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
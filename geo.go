@@ -0,0 +1,114 @@
+// db/geo.go
+package db
+
+import (
+	"database/sql"
+)
+
+// NearbyRecord is a record returned by FindNearby with its distance
+// from the search point
+type NearbyRecord struct {
+	*Record
+	DistanceMeters float64
+}
+
+// AddLocationColumn enables PostGIS and adds an indexed location column
+// (WGS 84 point) to the records table
+func AddLocationColumn(db *sql.DB) error {
+	queries := []string{
+		"CREATE EXTENSION IF NOT EXISTS postgis",
+		"ALTER TABLE records ADD COLUMN IF NOT EXISTS location geometry(Point, 4326)",
+		"CREATE INDEX IF NOT EXISTS records_location_gist ON records USING GIST (location)",
+	}
+	return execAll(db, queries)
+}
+
+// InsertRecordAt inserts a record together with its location
+func InsertRecordAt(db *sql.DB, record *Record, lat, lon float64) error {
+	query := `
+	INSERT INTO records (
+		name, description, amount, is_active, created_at, updated_at, location
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, ST_SetSRID(ST_MakePoint($7, $8), 4326)
+	) RETURNING id`
+
+	err := db.QueryRow(
+		query,
+		record.Name,
+		record.Description,
+		record.Amount,
+		record.IsActive,
+		record.CreatedAtUnix,
+		record.UpdatedAtUnix,
+		lon, // PostGIS points are (x, y) = (lon, lat)
+		lat,
+	).Scan(&record.ID)
+
+	return err
+}
+
+// SetRecordLocation sets or replaces the location of an existing record
+func SetRecordLocation(db *sql.DB, id int64, lat, lon float64) error {
+	result, err := db.Exec(
+		"UPDATE records SET location = ST_SetSRID(ST_MakePoint($1, $2), 4326) WHERE id = $3",
+		lon, lat, id,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, id)
+}
+
+// GetRecordLocation returns the latitude and longitude of a record.
+// ok is false when the record has no location.
+func GetRecordLocation(db *sql.DB, id int64) (lat, lon float64, ok bool, err error) {
+	var nlat, nlon sql.NullFloat64
+	err = db.QueryRow(
+		"SELECT ST_Y(location), ST_X(location) FROM records WHERE id = $1", id,
+	).Scan(&nlat, &nlon)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return nlat.Float64, nlon.Float64, nlat.Valid && nlon.Valid, nil
+}
+
+// FindNearby returns records within radius meters of the given point,
+// nearest first
+func FindNearby(db *sql.DB, lat, lon, radius float64) ([]*NearbyRecord, error) {
+	query := `
+		SELECT id, name, description, amount, is_active, created_at, updated_at,
+			ST_Distance(location::geography, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography) AS distance
+		FROM records
+		WHERE location IS NOT NULL
+			AND ST_DWithin(location::geography, ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography, $3)
+		ORDER BY distance
+	`
+
+	rows, err := db.Query(query, lon, lat, radius)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*NearbyRecord
+	for rows.Next() {
+		record := &NearbyRecord{Record: &Record{}}
+		err := rows.Scan(
+			&record.ID,
+			&record.Name,
+			&record.Description,
+			&record.Amount,
+			&record.IsActive,
+			&record.CreatedAtUnix,
+			&record.UpdatedAtUnix,
+			&record.DistanceMeters,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
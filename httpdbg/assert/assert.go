@@ -0,0 +1,149 @@
+// httpdbg/assert/assert.go
+package assert
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"your/path/to/httpdbg"
+)
+
+// TB is the part of testing.TB the helpers use
+type TB interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertStatus checks the response status code:
+//
+//	resp, _ := client.Get(url)
+//	c, _ := httpdbg.Capture(resp)
+//	assert.AssertStatus(t, c, http.StatusOK)
+func AssertStatus(t TB, c *httpdbg.CapturedExchange, want int) bool {
+	t.Helper()
+	if c.Status != want {
+		t.Errorf("%s %s: status %d, want %d%s", c.Method, c.URL, c.Status, want, bodyHint(c))
+		return false
+	}
+	return true
+}
+
+// AssertHeader checks a response header's value; a want of "" checks the
+// header is absent
+func AssertHeader(t TB, c *httpdbg.CapturedExchange, name, want string) bool {
+	t.Helper()
+	if got := c.ResponseHeader.Get(name); got != want {
+		t.Errorf("%s %s: header %s is %q, want %q", c.Method, c.URL, name, got, want)
+		return false
+	}
+	return true
+}
+
+// AssertJSONBody checks the value at path in the JSON response body.
+// Paths look like $.items[0].name (the leading $ is optional). want is
+// compared by its JSON encoding, so 1 matches 1.0 and a struct matches an
+// object with the same fields.
+func AssertJSONBody(t TB, c *httpdbg.CapturedExchange, path string, want any) bool {
+	t.Helper()
+
+	var body any
+	if err := json.Unmarshal(c.ResponseBody, &body); err != nil {
+		t.Errorf("%s %s: body is not JSON: %v", c.Method, c.URL, err)
+		return false
+	}
+	got, err := Lookup(body, path)
+	if err != nil {
+		t.Errorf("%s %s: %v", c.Method, c.URL, err)
+		return false
+	}
+
+	encoded, err := json.Marshal(want)
+	if err != nil {
+		t.Errorf("encoding want: %v", err)
+		return false
+	}
+	var normalized any
+	json.Unmarshal(encoded, &normalized)
+
+	if !reflect.DeepEqual(got, normalized) {
+		gotText, _ := json.Marshal(got)
+		t.Errorf("%s %s: %s is %s, want %s", c.Method, c.URL, path, gotText, encoded)
+		return false
+	}
+	return true
+}
+
+// AssertBodyContains checks the response body contains substr
+func AssertBodyContains(t TB, c *httpdbg.CapturedExchange, substr string) bool {
+	t.Helper()
+	if !strings.Contains(string(c.ResponseBody), substr) {
+		t.Errorf("%s %s: body does not contain %q%s", c.Method, c.URL, substr, bodyHint(c))
+		return false
+	}
+	return true
+}
+
+// Lookup returns the value at path in a decoded JSON document, e.g.
+// $.items[0].name
+func Lookup(doc any, path string) (any, error) {
+	rest := strings.TrimPrefix(path, "$")
+	cur := doc
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			obj, ok := cur.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an object at .%s", path, key)
+			}
+			if cur, ok = obj[key]; !ok {
+				return nil, fmt.Errorf("%s: no field %q", path, key)
+			}
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%s: unclosed [", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad index %q", path, rest[1:end])
+			}
+			arr, ok := cur.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: not an array at [%d]", path, i)
+			}
+			if i < 0 || i >= len(arr) {
+				return nil, fmt.Errorf("%s: index %d out of range (length %d)", path, i, len(arr))
+			}
+			cur = arr[i]
+			rest = rest[end+1:]
+		default:
+			// Allow a bare first key, as in items[0].name
+			rest = "." + rest
+		}
+	}
+	return cur, nil
+}
+
+// maxBodyHint is how much of a body failure messages show
+const maxBodyHint = 512
+
+// bodyHint shows the start of the response body in a failure message
+func bodyHint(c *httpdbg.CapturedExchange) string {
+	if len(c.ResponseBody) == 0 {
+		return ""
+	}
+	body := c.ResponseBody
+	if len(body) > maxBodyHint {
+		return fmt.Sprintf("\nbody: %s... (%d bytes)", body[:maxBodyHint], len(body))
+	}
+	return fmt.Sprintf("\nbody: %s", body)
+}
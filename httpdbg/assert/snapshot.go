@@ -0,0 +1,175 @@
+// httpdbg/assert/snapshot.go
+package assert
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"your/path/to/httpdbg"
+)
+
+// DefaultScrubbedFields are the JSON fields Snapshotter replaces by
+// default, as they change from run to run
+var DefaultScrubbedFields = []string{"created_at", "updated_at", "timestamp", "request_id"}
+
+// scrubbed replaces the value of a scrubbed field
+const scrubbed = "<scrubbed>"
+
+// Snapshotter compares responses against golden files, failing with a
+// diff when they change. Responses are normalized first: headers in
+// httpdbg.DiffIgnoredHeaders are dropped, JSON bodies are indented with
+// sorted keys, and scrubbed fields are blanked out.
+//
+// Set Update, or the UPDATE_SNAPSHOTS environment variable, to rewrite
+// the golden files after an intended change.
+type Snapshotter struct {
+	// Dir holds the golden files; defaults to testdata/snapshots
+	Dir string
+	// Scrub lists JSON field names whose values are replaced wherever they
+	// appear; defaults to DefaultScrubbedFields
+	Scrub []string
+	// Update rewrites golden files instead of comparing against them
+	Update bool
+}
+
+// snapshot is the golden file format
+type snapshot struct {
+	Status int               `json:"status"`
+	Header map[string]string `json:"header,omitempty"`
+	// Body is the decoded JSON body, or the body as a string
+	Body any `json:"body,omitempty"`
+}
+
+// AssertSnapshot checks c against the golden file name with the default
+// Snapshotter:
+//
+//	c, _ := httpdbg.Capture(resp)
+//	assert.AssertSnapshot(t, "get-user", c)
+func AssertSnapshot(t TB, name string, c *httpdbg.CapturedExchange) bool {
+	t.Helper()
+	return (&Snapshotter{}).Assert(t, name, c)
+}
+
+// Assert checks c against the golden file name. A missing golden file is
+// written and reported as a failure, so new snapshots get reviewed.
+func (s *Snapshotter) Assert(t TB, name string, c *httpdbg.CapturedExchange) bool {
+	t.Helper()
+
+	got := s.normalize(c)
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Errorf("snapshot %s: %v", name, err)
+		return false
+	}
+	data = append(data, '\n')
+
+	path := filepath.Join(s.dir(), name+".json")
+	want, err := os.ReadFile(path)
+	missing := os.IsNotExist(err)
+	if missing || s.Update || os.Getenv("UPDATE_SNAPSHOTS") != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Errorf("snapshot %s: %v", name, err)
+			return false
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Errorf("snapshot %s: %v", name, err)
+			return false
+		}
+		if missing {
+			t.Errorf("snapshot %s: created %s; review and commit it", name, path)
+			return false
+		}
+		return true
+	}
+	if err != nil {
+		t.Errorf("snapshot %s: %v", name, err)
+		return false
+	}
+	if bytes.Equal(want, data) {
+		return true
+	}
+
+	var golden snapshot
+	if err := json.Unmarshal(want, &golden); err != nil {
+		t.Errorf("snapshot %s: reading %s: %v", name, path, err)
+		return false
+	}
+	diff := httpdbg.Diff(golden.exchange(), got.exchange())
+	if diff == "" {
+		// Only formatting differs, e.g. the file was edited by hand
+		return true
+	}
+	t.Errorf("snapshot %s: response changed (set UPDATE_SNAPSHOTS=1 to accept):\n%s", name, diff)
+	return false
+}
+
+// dir returns the golden file directory
+func (s *Snapshotter) dir() string {
+	if s.Dir == "" {
+		return filepath.Join("testdata", "snapshots")
+	}
+	return s.Dir
+}
+
+// normalize reduces c to its stable parts
+func (s *Snapshotter) normalize(c *httpdbg.CapturedExchange) snapshot {
+	snap := snapshot{Status: c.Status, Header: make(map[string]string)}
+	for k := range c.ResponseHeader {
+		snap.Header[http.CanonicalHeaderKey(k)] = c.ResponseHeader.Get(k)
+	}
+	for _, k := range httpdbg.DiffIgnoredHeaders {
+		delete(snap.Header, http.CanonicalHeaderKey(k))
+	}
+
+	var body any
+	if err := json.Unmarshal(c.ResponseBody, &body); err == nil {
+		snap.Body = s.scrub(body)
+	} else if len(c.ResponseBody) > 0 {
+		snap.Body = string(c.ResponseBody)
+	}
+	return snap
+}
+
+// scrub blanks out the scrubbed fields of a decoded JSON value
+func (s *Snapshotter) scrub(v any) any {
+	fields := s.Scrub
+	if fields == nil {
+		fields = DefaultScrubbedFields
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = s.scrub(e)
+			for _, f := range fields {
+				if k == f {
+					v[k] = scrubbed
+				}
+			}
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = s.scrub(e)
+		}
+	}
+	return v
+}
+
+// exchange turns a snapshot back into a capture for httpdbg.Diff
+func (snap snapshot) exchange() *httpdbg.CapturedExchange {
+	c := &httpdbg.CapturedExchange{Status: snap.Status, ResponseHeader: make(http.Header)}
+	for k, v := range snap.Header {
+		c.ResponseHeader.Set(k, v)
+	}
+	switch body := snap.Body.(type) {
+	case nil:
+	case string:
+		c.ResponseBody = []byte(body)
+	default:
+		c.ResponseBody, _ = json.Marshal(body)
+	}
+	return c
+}
@@ -0,0 +1,162 @@
+// httpdbg/async.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultAsyncQueue is the number of log entries Async mode queues when
+// AsyncQueue is zero
+const DefaultAsyncQueue = 1024
+
+// asyncLog is the queue and background writer behind Async mode
+type asyncLog struct {
+	// mu is held for reading while sending on queue, and for writing to
+	// close it, so nothing is sent after Close
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan func()
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// asyncLogger hands structured entries to the background writer
+type asyncLogger struct {
+	d *DebugTransport
+	l Logger
+}
+
+// Log queues the entry for the wrapped logger
+func (a asyncLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	a.d.enqueue(func() {
+		a.l.Log(ctx, level, msg, fields...)
+	})
+}
+
+// startAsync returns the background writer, starting it on first use
+func (d *DebugTransport) startAsync() *asyncLog {
+	d.asyncOnce.Do(func() {
+		size := d.AsyncQueue
+		if size <= 0 {
+			size = DefaultAsyncQueue
+		}
+		a := &asyncLog{queue: make(chan func(), size), done: make(chan struct{})}
+		go func() {
+			defer close(a.done)
+			for write := range a.queue {
+				write()
+			}
+		}()
+		d.async = a
+	})
+	return d.async
+}
+
+// entry returns the writer for one text log entry and a function to call
+// once it is complete. Synchronous entries are written to the output
+// under d.mu; async ones are collected and queued whole.
+func (d *DebugTransport) entry(x *exchange) (io.Writer, func()) {
+	// A request block held back by GroupExchanges goes out first
+	held := x.held
+	x.held = nil
+
+	if !d.Async {
+		d.mu.Lock()
+		if held != nil {
+			x.w.Write(held.Bytes())
+		}
+		return x.w, d.mu.Unlock
+	}
+
+	var buf bytes.Buffer
+	if held != nil {
+		buf.Write(held.Bytes())
+	}
+	return &buf, func() {
+		d.enqueue(func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			x.w.Write(buf.Bytes())
+		})
+	}
+}
+
+// requestEntry is entry for a request block, which GroupExchanges holds
+// back to be written with the next block of the exchange
+func (d *DebugTransport) requestEntry(x *exchange) (io.Writer, func()) {
+	if !d.GroupExchanges {
+		return d.entry(x)
+	}
+	x.held = new(bytes.Buffer)
+	return x.held, func() {}
+}
+
+// enqueue queues write for the background writer, dropping it if the
+// queue is full. After Close, write runs synchronously instead.
+func (d *DebugTransport) enqueue(write func()) {
+	a := d.startAsync()
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if a.closed {
+		write()
+		return
+	}
+	select {
+	case a.queue <- write:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Dropped returns how many log entries Async mode has dropped because its
+// queue was full
+func (d *DebugTransport) Dropped() int64 {
+	if !d.Async {
+		return 0
+	}
+	return d.startAsync().dropped.Load()
+}
+
+// Flush waits until the log entries queued in Async mode have been written
+func (d *DebugTransport) Flush() {
+	if !d.Async {
+		return
+	}
+	a := d.startAsync()
+
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return
+	}
+	flushed := make(chan struct{})
+	a.queue <- func() { close(flushed) }
+	a.mu.RUnlock()
+
+	<-flushed
+}
+
+// Close writes the log entries queued in Async mode and stops the
+// background writer. Entries logged afterwards are written synchronously.
+func (d *DebugTransport) Close() error {
+	if !d.Async {
+		return nil
+	}
+	a := d.startAsync()
+
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	return nil
+}
@@ -0,0 +1,118 @@
+// httpdbg/auth.go
+package httpdbg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// AuthTransport attaches a token to every request, logging in when it has
+// none. A 401 response triggers one refresh and one retry, as long as the
+// request body can be replayed. Tokens in the Authorization header are
+// masked by DebugTransport's default redaction; add any custom header
+// used by Apply to Redaction.Headers.
+//
+//	client := &http.Client{Transport: &httpdbg.AuthTransport{
+//		Transport: &httpdbg.DebugTransport{},
+//		Login:     login,
+//	}}
+type AuthTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Login obtains a new token, e.g. by calling the API's login endpoint
+	Login func(ctx context.Context) (string, error)
+	// Apply attaches the token to a request; defaults to setting
+	// "Authorization: Bearer <token>"
+	Apply func(req *http.Request, token string)
+
+	mu    sync.Mutex
+	token string
+}
+
+// Token returns the current token, logging in if there is none
+func (t *AuthTransport) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" {
+		return t.token, nil
+	}
+	return t.login(ctx)
+}
+
+// refresh replaces a rejected token. If another request already
+// refreshed it, the newer token is reused rather than logging in again.
+func (t *AuthTransport) refresh(ctx context.Context, rejected string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.token != "" && t.token != rejected {
+		return t.token, nil
+	}
+	return t.login(ctx)
+}
+
+// login calls Login and stores the token; t.mu must be held
+func (t *AuthTransport) login(ctx context.Context) (string, error) {
+	if t.Login == nil {
+		return "", fmt.Errorf("AuthTransport has no Login function")
+	}
+	token, err := t.Login(ctx)
+	if err != nil {
+		return "", fmt.Errorf("login failed: %v", err)
+	}
+	t.token = token
+	return token, nil
+}
+
+// RoundTrip sends req with the token, refreshing it once on a 401
+func (t *AuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	token, err := t.Token(req.Context())
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	resp, err := transport.RoundTrip(t.authorize(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	// Retry only if the body can be sent again
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil
+	}
+	token, err = t.refresh(req.Context(), token)
+	if err != nil {
+		return resp, nil
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	retry := t.authorize(req, token)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		retry.Body = body
+	}
+	return transport.RoundTrip(retry)
+}
+
+// authorize returns a copy of req carrying token
+func (t *AuthTransport) authorize(req *http.Request, token string) *http.Request {
+	req = req.Clone(req.Context())
+	if t.Apply != nil {
+		t.Apply(req, token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
@@ -0,0 +1,117 @@
+// httpdbg/bandwidth.go
+package httpdbg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NetworkProfile describes a network link for BandwidthTransport to
+// simulate. Rates are in bytes per second; zero leaves that direction
+// unlimited.
+type NetworkProfile struct {
+	Download int64
+	Upload   int64
+	// Latency delays each request before it is sent
+	Latency time.Duration
+}
+
+// Network profiles matching the browser developer tools presets
+var (
+	ProfileSlow3G = NetworkProfile{Download: 50_000, Upload: 50_000, Latency: 2 * time.Second}
+	ProfileFast3G = NetworkProfile{Download: 180_000, Upload: 84_375, Latency: 563 * time.Millisecond}
+	Profile4G     = NetworkProfile{Download: 500_000, Upload: 375_000, Latency: 20 * time.Millisecond}
+)
+
+// BandwidthTransport simulates a slow network: it delays each request by
+// the profile's latency and paces request and response bodies to its
+// upload and download rates, so timeouts and progress reporting can be
+// exercised without external tools. Concurrent requests share the
+// bandwidth, as they would share a link. Headers aren't paced.
+type BandwidthTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	Profile   NetworkProfile
+
+	once             sync.Once
+	download, upload *tokenBucket
+}
+
+// RoundTrip waits out the latency, then sends req with its body paced to
+// the upload rate and returns the response with its body paced to the
+// download rate
+func (t *BandwidthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	t.once.Do(func() {
+		t.download = byteBucket(t.Profile.Download)
+		t.upload = byteBucket(t.Profile.Upload)
+	})
+	ctx := req.Context()
+
+	if t.Profile.Latency > 0 {
+		timer := time.NewTimer(t.Profile.Latency)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			closeBody(req)
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if t.upload != nil && req.Body != nil && req.Body != http.NoBody {
+		req = req.WithContext(ctx)
+		req.Body = &pacedBody{ReadCloser: req.Body, ctx: ctx, bucket: t.upload}
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil || t.download == nil {
+		return resp, err
+	}
+	resp.Body = &pacedBody{ReadCloser: resp.Body, ctx: ctx, bucket: t.download}
+	return resp, nil
+}
+
+// byteBucket returns a bucket handing out rate bytes a second, in bursts
+// of a tenth of a second, or nil for no limit
+func byteBucket(rate int64) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return newTokenBucket(Rate{PerSecond: float64(rate), Burst: int(max(rate/10, 1))})
+}
+
+// pacedBody delivers a body no faster than its bucket allows
+type pacedBody struct {
+	io.ReadCloser
+	ctx    context.Context
+	bucket *tokenBucket
+}
+
+// Read reads up to one burst, then holds it until the bucket covers it
+func (b *pacedBody) Read(p []byte) (int, error) {
+	if burst := b.bucket.rate.Burst; len(p) > burst {
+		p = p[:burst]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if werr := b.bucket.waitN(b.ctx, float64(n)); werr != nil {
+			return 0, werr
+		}
+	}
+	return n, err
+}
+
+// WithBandwidth simulates the network described by profile for the
+// client's requests
+func WithBandwidth(profile NetworkProfile) Option {
+	return func(c *clientConfig) {
+		c.bandwidth = &profile
+	}
+}
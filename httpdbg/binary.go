@@ -0,0 +1,86 @@
+// httpdbg/binary.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// binaryContentTypes are media types that are never logged as text
+var binaryContentTypes = map[string]bool{
+	"application/octet-stream":        true,
+	"application/zip":                 true,
+	"application/gzip":                true,
+	"application/pdf":                 true,
+	"application/protobuf":            true,
+	"application/x-protobuf":          true,
+	"application/vnd.google.protobuf": true,
+	"application/grpc":                true,
+	"application/msgpack":             true,
+	"application/x-msgpack":           true,
+	"application/cbor":                true,
+}
+
+// BodyDecoder turns binary bodies into text for the logs. req is the
+// exchange's request and resp its response, or nil when body is the
+// request's own. DecodeBody returns false for bodies it doesn't handle,
+// which are then summarized as usual. JSON output passes through
+// Redaction like any other JSON body.
+type BodyDecoder interface {
+	DecodeBody(req *http.Request, resp *http.Response, body []byte) ([]byte, bool)
+}
+
+// decodeBinary renders a binary body with the BodyDecoder, if any
+func (d *DebugTransport) decodeBinary(x *exchange, resp *http.Response, body []byte) ([]byte, bool) {
+	if d.BodyDecoder == nil || x.req == nil {
+		return nil, false
+	}
+	return d.BodyDecoder.DecodeBody(x.req, resp, body)
+}
+
+// isBinary reports whether a body should be summarized instead of printed.
+// The content type decides when it is conclusive; otherwise the bytes are
+// treated as binary if they are not valid UTF-8 or contain NUL bytes.
+func isBinary(contentType string, body []byte) bool {
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err == nil {
+			switch {
+			case binaryContentTypes[mediaType]:
+				return true
+			case strings.HasPrefix(mediaType, "image/"),
+				strings.HasPrefix(mediaType, "audio/"),
+				strings.HasPrefix(mediaType, "video/"),
+				strings.HasPrefix(mediaType, "font/"):
+				// SVG is XML and reads fine as text
+				return mediaType != "image/svg+xml"
+			case strings.HasPrefix(mediaType, "text/"):
+				return false
+			}
+		}
+	}
+
+	return !utf8.Valid(body) || bytes.IndexByte(body, 0) >= 0
+}
+
+// binarySummary describes a binary body, with a hex dump of up to preview
+// leading bytes
+func binarySummary(contentType string, body []byte, preview int) string {
+	if contentType == "" {
+		contentType = "unknown content type"
+	}
+	summary := fmt.Sprintf("[binary body: %s, %d bytes]", contentType, len(body))
+
+	if preview <= 0 {
+		return summary
+	}
+	if preview > len(body) {
+		preview = len(body)
+	}
+	return summary + "\n" + strings.TrimRight(hex.Dump(body[:preview]), "\n")
+}
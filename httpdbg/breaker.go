@@ -0,0 +1,178 @@
+// httpdbg/breaker.go
+package httpdbg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped with the host, for requests refused
+// by an open circuit
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitState is the state of a host's circuit
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails requests fast until OpenTimeout has passed
+	CircuitOpen
+	// CircuitHalfOpen lets a single probe through to test recovery
+	CircuitHalfOpen
+)
+
+// String returns the lower-case state name
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// CircuitBreakerTransport stops sending requests to a host after
+// FailureThreshold consecutive failures. Once OpenTimeout has passed, one
+// probe request is let through: success closes the circuit again, failure
+// reopens it. Each host has its own circuit.
+type CircuitBreakerTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// FailureThreshold is the consecutive failures that open the circuit,
+	// defaults to 5
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open, defaults to 30s
+	OpenTimeout time.Duration
+	// IsFailure classifies an outcome; by default transport errors and
+	// 5xx responses are failures
+	IsFailure func(resp *http.Response, err error) bool
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+// circuit is the breaker state of one host
+type circuit struct {
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// State returns the current circuit state for host
+func (t *CircuitBreakerTransport) State(host string) CircuitState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.hosts[host]
+	if !ok {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= t.openTimeout() {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// RoundTrip sends req unless its host's circuit is open
+func (t *CircuitBreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	host := req.URL.Host
+	if !t.allow(host) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, host)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	t.record(host, t.failed(resp, err))
+	return resp, err
+}
+
+// allow decides whether a request to host may be sent
+func (t *CircuitBreakerTransport) allow(host string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuit(host)
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < t.openTimeout() {
+			return false
+		}
+		c.state = CircuitHalfOpen
+		fallthrough
+	case CircuitHalfOpen:
+		// Only one probe at a time
+		if c.probing {
+			return false
+		}
+		c.probing = true
+	}
+	return true
+}
+
+// record updates host's circuit with the outcome of a request
+func (t *CircuitBreakerTransport) record(host string, failed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c := t.circuit(host)
+	c.probing = false
+	if !failed {
+		c.state, c.failures = CircuitClosed, 0
+		return
+	}
+
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= t.failureThreshold() {
+		c.state, c.openedAt = CircuitOpen, time.Now()
+	}
+}
+
+// circuit returns host's circuit, creating it closed; t.mu must be held
+func (t *CircuitBreakerTransport) circuit(host string) *circuit {
+	if t.hosts == nil {
+		t.hosts = make(map[string]*circuit)
+	}
+	c, ok := t.hosts[host]
+	if !ok {
+		c = &circuit{}
+		t.hosts[host] = c
+	}
+	return c
+}
+
+// failed classifies an outcome with IsFailure or the default rule
+func (t *CircuitBreakerTransport) failed(resp *http.Response, err error) bool {
+	if t.IsFailure != nil {
+		return t.IsFailure(resp, err)
+	}
+	return err != nil || resp.StatusCode >= 500
+}
+
+// failureThreshold returns FailureThreshold or its default
+func (t *CircuitBreakerTransport) failureThreshold() int {
+	if t.FailureThreshold > 0 {
+		return t.FailureThreshold
+	}
+	return 5
+}
+
+// openTimeout returns OpenTimeout or its default
+func (t *CircuitBreakerTransport) openTimeout() time.Duration {
+	if t.OpenTimeout > 0 {
+		return t.OpenTimeout
+	}
+	return 30 * time.Second
+}
@@ -0,0 +1,123 @@
+// httpdbg/budget.go
+package httpdbg
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// captureSkipped stands in for a body that wasn't captured
+const captureSkipped = "[not captured: MaxCaptureMemory reached]"
+
+// captureBudget counts the bytes held by captured bodies against
+// MaxCaptureMemory
+type captureBudget struct {
+	used atomic.Int64
+}
+
+// reserve charges n bytes, reporting false (and charging nothing) if
+// that would take the total past max. A non-positive max is no limit.
+func (b *captureBudget) reserve(max, n int64) bool {
+	if max <= 0 || n <= 0 {
+		return true
+	}
+	if b.used.Add(n) > max {
+		b.used.Add(-n)
+		return false
+	}
+	return true
+}
+
+// free returns n bytes to the budget
+func (b *captureBudget) free(n int64) {
+	if n > 0 {
+		b.used.Add(-n)
+	}
+}
+
+// CaptureMemory returns how many bytes of bodies are currently held for
+// logging and OnCapture, as counted against MaxCaptureMemory. It is only
+// tracked while MaxCaptureMemory is set.
+func (d *DebugTransport) CaptureMemory() int64 {
+	return d.memory.used.Load()
+}
+
+// newCaptureBuffer returns a buffer for up to MaxBodyLog bytes of a body,
+// charged to the capture budget
+func (d *DebugTransport) newCaptureBuffer() *limitedBuffer {
+	return &limitedBuffer{limit: d.bodyLimit(), budget: &d.memory, max: d.MaxCaptureMemory}
+}
+
+// captureBody reads body into a pooled buffer held by refs holders and
+// charged to the capture budget, returning it and the body to pass on in
+// place of the original. If the budget runs out first, it returns a nil
+// buffer and a body that replays what was read before the rest.
+func (d *DebugTransport) captureBody(body io.ReadCloser, refs int32) (*pooledBuffer, io.ReadCloser) {
+	if d.MaxCaptureMemory <= 0 {
+		p := readPooled(body, refs)
+		body.Close()
+		return p, p.body()
+	}
+
+	r := &budgetReader{r: body, budget: &d.memory, max: d.MaxCaptureMemory}
+	p := readPooled(r, refs)
+	reserved := r.reserved
+	p.free = func() { d.memory.free(reserved) }
+	if !r.exhausted {
+		body.Close()
+		return p, p.body()
+	}
+
+	// Only the replaying body holds the buffer now
+	p.refs = 1
+	return nil, &replayBody{Reader: io.MultiReader(bytes.NewReader(p.Bytes()), body), body: body, p: p}
+}
+
+// maxBudgetRead caps each read of a budgetReader, so a large buffer
+// doesn't reserve far more than the body needs
+const maxBudgetRead = 32 << 10
+
+// budgetReader reads from r for as long as the budget covers what is read
+type budgetReader struct {
+	r         io.Reader
+	budget    *captureBudget
+	max       int64
+	reserved  int64
+	exhausted bool
+}
+
+func (b *budgetReader) Read(p []byte) (int, error) {
+	if b.exhausted {
+		return 0, io.EOF
+	}
+	// Reserve room for a full read, then give back what went unused
+	if len(p) > maxBudgetRead {
+		p = p[:maxBudgetRead]
+	}
+	if !b.budget.reserve(b.max, int64(len(p))) {
+		b.exhausted = true
+		return 0, io.EOF
+	}
+	n, err := b.r.Read(p)
+	b.budget.free(int64(len(p) - n))
+	b.reserved += int64(n)
+	return n, err
+}
+
+// replayBody serves the part of a body captured before the budget ran
+// out, then the rest of the original body
+type replayBody struct {
+	io.Reader
+	body io.Closer
+	p    *pooledBuffer
+	once sync.Once
+}
+
+// Close closes the original body and releases the captured part, if
+// pooled
+func (b *replayBody) Close() error {
+	b.once.Do(b.p.release)
+	return b.body.Close()
+}
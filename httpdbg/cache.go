@@ -0,0 +1,303 @@
+// httpdbg/cache.go
+package httpdbg
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatusHeader is set on responses passing through CachingTransport
+// to HIT, MISS, REVALIDATED or BYPASS. Put a DebugTransport in front of
+// the cache to see it in the logs.
+const CacheStatusHeader = "X-Httpdbg-Cache"
+
+// cachedAtHeader records when a stored response was received
+const cachedAtHeader = "X-Httpdbg-Cached-At"
+
+// cacheableStatus lists the status codes cached by default (RFC 7231 6.1)
+var cacheableStatus = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// CachingTransport is a private HTTP cache following RFC 7234. GET
+// responses are stored when they carry freshness information or
+// validators, served while fresh, and revalidated with If-None-Match /
+// If-Modified-Since once stale. Successful unsafe requests invalidate the
+// cached URL. Served responses keep the X-Httpdbg-* bookkeeping headers
+// stored with them.
+type CachingTransport struct {
+	// Transport sends requests that miss the cache; defaults to
+	// http.DefaultTransport
+	Transport http.RoundTripper
+	// Storage holds the responses; defaults to a MemoryCache
+	Storage CacheStorage
+
+	once sync.Once
+}
+
+// RoundTrip answers req from the cache where possible
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	t.once.Do(func() {
+		if t.Storage == nil {
+			t.Storage = NewMemoryCache()
+		}
+	})
+
+	key := req.URL.String()
+	if req.Method != http.MethodGet {
+		resp, err := transport.RoundTrip(req)
+		if err == nil && req.Method != http.MethodHead && resp.StatusCode < 400 {
+			t.Storage.Delete(key)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok {
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Header.Set(CacheStatusHeader, "BYPASS")
+		return resp, nil
+	}
+
+	cached := t.load(key, req)
+	if cached != nil {
+		_, noCache := reqCC["no-cache"]
+		if !noCache && freshness(cached) > age(cached) {
+			cached.Header.Set("Age", strconv.Itoa(int(age(cached).Seconds())))
+			cached.Header.Set(CacheStatusHeader, "HIT")
+			return cached, nil
+		}
+
+		// Stale: ask the origin whether it changed
+		if etag := cached.Header.Get("ETag"); etag != "" || cached.Header.Get("Last-Modified") != "" {
+			cond := req.Clone(req.Context())
+			if etag != "" {
+				cond.Header.Set("If-None-Match", etag)
+			}
+			if lm := cached.Header.Get("Last-Modified"); lm != "" {
+				cond.Header.Set("If-Modified-Since", lm)
+			}
+
+			resp, err := transport.RoundTrip(cond)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode == http.StatusNotModified {
+				resp.Body.Close()
+				// Refresh the stored headers from the 304
+				for k, v := range resp.Header {
+					cached.Header[k] = v
+				}
+				cached.Header.Set(cachedAtHeader, time.Now().UTC().Format(time.RFC3339Nano))
+				t.store(key, cached)
+				cached.Header.Del("Age")
+				cached.Header.Set(CacheStatusHeader, "REVALIDATED")
+				return cached, nil
+			}
+			cached.Body.Close()
+			return t.storeResponse(key, req, resp)
+		}
+		cached.Body.Close()
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return t.storeResponse(key, req, resp)
+}
+
+// storeResponse caches resp if allowed and marks it a miss
+func (t *CachingTransport) storeResponse(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	resp.Header.Set(CacheStatusHeader, "MISS")
+	if !storable(req, resp) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	stored := *resp
+	stored.Header = resp.Header.Clone()
+	stored.Header.Del(CacheStatusHeader)
+	stored.Header.Set(cachedAtHeader, time.Now().UTC().Format(time.RFC3339Nano))
+	// Remember the request headers the response varies on
+	for _, name := range varyHeaders(resp) {
+		stored.Header.Set("X-Httpdbg-Vary-"+name, req.Header.Get(name))
+	}
+	stored.Body = io.NopCloser(bytes.NewReader(body))
+	t.store(key, &stored)
+	return resp, nil
+}
+
+// store serializes resp into the storage
+func (t *CachingTransport) store(key string, resp *http.Response) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	copied := *resp
+	copied.Body = io.NopCloser(bytes.NewReader(body))
+	dump, err := httputil.DumpResponse(&copied, true)
+	if err != nil {
+		return
+	}
+	t.Storage.Set(key, dump)
+}
+
+// load returns the stored response for key if it matches req's Vary headers
+func (t *CachingTransport) load(key string, req *http.Request) *http.Response {
+	dump, ok := t.Storage.Get(key)
+	if !ok {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(dump)), req)
+	if err != nil {
+		t.Storage.Delete(key)
+		return nil
+	}
+
+	for _, name := range varyHeaders(resp) {
+		if resp.Header.Get("X-Httpdbg-Vary-"+name) != req.Header.Get(name) {
+			resp.Body.Close()
+			return nil
+		}
+	}
+
+	// Keep the body replayable for later stores
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp
+}
+
+// storable reports whether a response may be cached
+func storable(req *http.Request, resp *http.Response) bool {
+	if !cacheableStatus[resp.StatusCode] {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyHeaders(resp) {
+		if name == "*" {
+			return false
+		}
+	}
+	if req.Header.Get("Authorization") != "" {
+		// Shared-cache rules don't bind a private cache, but only cache
+		// authenticated responses that say so explicitly
+		if _, ok := cc["private"]; !ok {
+			if _, ok := cc["max-age"]; !ok {
+				return false
+			}
+		}
+	}
+
+	_, maxAge := cc["max-age"]
+	return maxAge || resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != ""
+}
+
+// freshness returns how long a stored response stays fresh (RFC 7234 4.2.1)
+func freshness(resp *http.Response) time.Duration {
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return 0
+	}
+	if v, ok := cc["max-age"]; ok {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+		return 0
+	}
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		date = cachedAt(resp)
+	}
+	if v := resp.Header.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+
+	// Heuristic: a tenth of the time since the last modification
+	if lm, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil && lm.Before(date) {
+		return date.Sub(lm) / 10
+	}
+	return 0
+}
+
+// age returns a stored response's current age (RFC 7234 4.2.3)
+func age(resp *http.Response) time.Duration {
+	initial := time.Duration(0)
+	if v, err := strconv.Atoi(resp.Header.Get("Age")); err == nil {
+		initial = time.Duration(v) * time.Second
+	}
+	return initial + time.Since(cachedAt(resp))
+}
+
+// cachedAt returns when a stored response was received
+func cachedAt(resp *http.Response) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, resp.Header.Get(cachedAtHeader))
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// parseCacheControl splits a Cache-Control header into directives
+func parseCacheControl(h http.Header) map[string]string {
+	cc := make(map[string]string)
+	for _, line := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+// varyHeaders returns the canonical header names listed in Vary
+func varyHeaders(resp *http.Response) []string {
+	var names []string
+	for _, line := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,96 @@
+// httpdbg/cachestore.go
+package httpdbg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheStorage holds the serialized responses kept by CachingTransport
+type CacheStorage interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// MemoryCache is an in-memory CacheStorage
+type MemoryCache struct {
+	mu sync.RWMutex
+	m  map[string][]byte
+}
+
+// NewMemoryCache returns an empty in-memory cache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{m: make(map[string][]byte)}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.m[key]
+	return v, ok
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[key] = value
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, key)
+}
+
+// DiskCache is a CacheStorage keeping one file per entry in Dir, so the
+// cache survives restarts. Write errors are ignored; a failed write is
+// just a later cache miss.
+type DiskCache struct {
+	Dir string
+}
+
+// NewDiskCache returns a cache in dir, creating the directory if needed
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{Dir: dir}, nil
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	v, err := os.ReadFile(c.path(key))
+	return v, err == nil
+}
+
+func (c *DiskCache) Set(key string, value []byte) {
+	// Write then rename so readers never see a partial entry
+	tmp, err := os.CreateTemp(c.Dir, "entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(value)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// path maps a key to its file name
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:]))
+}
@@ -0,0 +1,261 @@
+// httpdbg/capture.go
+package httpdbg
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// CapturedExchange is a copy of one request and its response, kept for
+// inspection after the exchange is over. Unlike the logs it is not
+// redacted, and bodies are as sent and received (possibly compressed).
+type CapturedExchange struct {
+	Method        string
+	URL           string
+	RequestHeader http.Header
+	RequestBody   []byte
+	// RequestSize is how many body bytes were sent, which may be more
+	// than RequestBody holds
+	RequestSize int64
+
+	// Status is zero, and Err set, when the request failed
+	Status         int
+	Proto          string
+	ResponseHeader http.Header
+	ResponseBody   []byte
+	// ResponseSize is how many body bytes were read, which may be more
+	// than ResponseBody holds
+	ResponseSize int64
+	Err          error
+
+	Start time.Time
+	// Duration is the time until the response headers arrived
+	Duration time.Duration
+}
+
+// Capture copies resp and the request that produced it, reading the
+// response body in full and leaving resp.Body ready to read again. The
+// request body is only captured if the request has GetBody.
+func Capture(resp *http.Response) (*CapturedExchange, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &CapturedExchange{
+		Status:         resp.StatusCode,
+		Proto:          resp.Proto,
+		ResponseHeader: resp.Header.Clone(),
+		ResponseBody:   body,
+		ResponseSize:   int64(len(body)),
+	}
+	if req := resp.Request; req != nil {
+		c.Method, c.URL, c.RequestHeader = req.Method, req.URL.String(), req.Header.Clone()
+		if req.GetBody != nil {
+			if rc, err := req.GetBody(); err == nil {
+				c.RequestBody, _ = io.ReadAll(rc)
+				c.RequestSize = int64(len(c.RequestBody))
+				rc.Close()
+			}
+		}
+	}
+	return c, nil
+}
+
+// OnCapture registers fn to receive a copy of every exchange once its
+// response body has been read to the end or closed, or the request has
+// failed. Bodies are captured up to MaxBodyLog bytes as they pass through
+// (request bodies from GetBody, where there is one), whether or not the
+// exchange is logged.
+func (d *DebugTransport) OnCapture(fn func(*CapturedExchange)) {
+	d.hookMu.Lock()
+	defer d.hookMu.Unlock()
+	d.hooks.onCapture = append(d.hooks.onCapture, fn)
+}
+
+// limitedBuffer keeps the first limit bytes written to it, or all of
+// them for a negative limit, and counts everything written. With a
+// budget it stops keeping bytes once the budget runs out. It is safe for
+// concurrent use, as a transport may still be sending a request body
+// while its response is read.
+type limitedBuffer struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	limit int
+	total int64
+
+	budget    *captureBudget
+	max       int64
+	exhausted bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total += int64(len(p))
+	n := len(p)
+	if b.limit >= 0 {
+		if room := b.limit - b.buf.Len(); n > room {
+			n = room
+		}
+	}
+	if n > 0 && b.budget != nil && !b.exhausted && !b.budget.reserve(b.max, int64(n)) {
+		b.exhausted = true
+	}
+	if n > 0 && !b.exhausted {
+		b.buf.Write(p[:n])
+	}
+	return len(p), nil
+}
+
+// free drops what was kept, returning it to the budget
+func (b *limitedBuffer) free() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.budget != nil && b.max > 0 {
+		b.budget.free(int64(b.buf.Len()))
+	}
+	b.buf = bytes.Buffer{}
+	b.exhausted = true
+}
+
+// complete reports whether every byte written was kept
+func (b *limitedBuffer) complete() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return int64(b.buf.Len()) == b.total
+}
+
+// Bytes returns a copy of what was kept
+func (b *limitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes())
+}
+
+// Total returns how many bytes were written, kept or not
+func (b *limitedBuffer) Total() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+// teeBody copies what is read from a body into a buffer
+type teeBody struct {
+	io.ReadCloser
+	w io.Writer
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.w.Write(p[:n])
+	return n, err
+}
+
+// capturing follows one exchange for OnCapture
+type capturing struct {
+	c       CapturedExchange
+	reqBody *limitedBuffer
+	// reqSize is the declared size of a request body copied from GetBody,
+	// which may hold more than reqBody kept
+	reqSize int64
+	fns     []func(*CapturedExchange)
+	once    sync.Once
+}
+
+// captureRequest starts a capture of req, returning the request to send
+// in its place. A body GetBody can replay is copied from there, leaving
+// the one sent (and any the transport resends) alone; other bodies are
+// copied as they are sent.
+func (d *DebugTransport) captureRequest(req *http.Request, fns []func(*CapturedExchange)) (*http.Request, *capturing) {
+	c := &capturing{
+		c: CapturedExchange{
+			Method:        req.Method,
+			URL:           req.URL.String(),
+			RequestHeader: req.Header.Clone(),
+			Start:         time.Now(),
+		},
+		reqBody: d.newCaptureBuffer(),
+		fns:     fns,
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, c
+	}
+
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			var r io.Reader = rc
+			if limit := d.bodyLimit(); limit >= 0 {
+				r = io.LimitReader(rc, int64(limit))
+			}
+			io.Copy(c.reqBody, r)
+			rc.Close()
+			c.reqSize = req.ContentLength
+			return req, c
+		}
+	}
+
+	req = req.WithContext(req.Context())
+	req.Body = &teeBody{ReadCloser: req.Body, w: c.reqBody}
+	return req, c
+}
+
+// captureResponse records the outcome and, once the response body is done,
+// hands the capture over. It returns the response to pass on.
+func (d *DebugTransport) captureResponse(c *capturing, resp *http.Response, duration time.Duration, err error) *http.Response {
+	c.c.Duration, c.c.Err = duration, err
+	if err != nil {
+		c.deliver(nil)
+		return resp
+	}
+
+	c.c.Status, c.c.Proto, c.c.ResponseHeader = resp.StatusCode, resp.Proto, resp.Header.Clone()
+	respBody := d.newCaptureBuffer()
+	resp.Body = &capturedBody{teeBody: teeBody{ReadCloser: resp.Body, w: respBody}, c: c, buf: respBody}
+	return resp
+}
+
+// deliver completes the capture and passes it to the callbacks once
+func (c *capturing) deliver(respBody *limitedBuffer) {
+	c.once.Do(func() {
+		c.c.RequestBody, c.c.RequestSize = c.reqBody.Bytes(), max(c.reqBody.Total(), c.reqSize)
+		if respBody != nil {
+			c.c.ResponseBody, c.c.ResponseSize = respBody.Bytes(), respBody.Total()
+		}
+		c.reqBody.free()
+		respBody.free()
+		for _, fn := range c.fns {
+			fn(&c.c)
+		}
+	})
+}
+
+// capturedBody delivers the capture at the end of the response body
+type capturedBody struct {
+	teeBody
+	c   *capturing
+	buf *limitedBuffer
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.teeBody.Read(p)
+	if err == io.EOF {
+		b.c.deliver(b.buf)
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.teeBody.Close()
+	b.c.deliver(b.buf)
+	return err
+}
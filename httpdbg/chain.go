@@ -0,0 +1,40 @@
+// httpdbg/chain.go
+package httpdbg
+
+import (
+	"net/http"
+)
+
+// Middleware wraps a RoundTripper with extra behaviour. Each transport in
+// this package can be made into one by setting its Transport field:
+//
+//	func(next http.RoundTripper) http.RoundTripper {
+//		return &httpdbg.RetryTransport{Transport: next, MaxAttempts: 3}
+//	}
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps base in middlewares. The first middleware is the outermost:
+// it sees each request first and each response last. A nil base is
+// http.DefaultTransport.
+//
+//	client := &http.Client{Transport: httpdbg.Chain(nil, debug, auth, retry)}
+//
+// sends requests through debug, then auth, then retry.
+func Chain(base http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rt := base
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
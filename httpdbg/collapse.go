@@ -0,0 +1,86 @@
+// httpdbg/collapse.go
+package httpdbg
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// collapseKey identifies identical requests: same method, URL and body
+type collapseKey [sha256.Size]byte
+
+// repeats tracks, per request, when it was last logged in full and how
+// many identical requests have been held back since
+type repeats struct {
+	mu      sync.Mutex
+	entries map[collapseKey]*repeat
+}
+
+type repeat struct {
+	logged time.Time
+	count  int
+}
+
+// maxRepeats is the number of tracked requests above which entries
+// outside the window are pruned
+const maxRepeats = 1024
+
+// newCollapseKey hashes the parts of req that make it a repeat
+func newCollapseKey(req *http.Request, body []byte) collapseKey {
+	h := sha256.New()
+	io.WriteString(h, req.Method)
+	h.Write([]byte{0})
+	io.WriteString(h, req.URL.String())
+	h.Write([]byte{0})
+	h.Write(body)
+
+	var key collapseKey
+	h.Sum(key[:0])
+	return key
+}
+
+// collapse reports whether the request with key repeats one logged within
+// CollapseWindow, and so shouldn't be dumped. Otherwise the request will
+// be logged, and collapse returns how many repeats were held back since
+// the last time.
+func (d *DebugTransport) collapse(key collapseKey) (collapsed bool, held int) {
+	d.repeats.mu.Lock()
+	defer d.repeats.mu.Unlock()
+
+	now := time.Now()
+	if r, ok := d.repeats.entries[key]; ok && now.Sub(r.logged) < d.CollapseWindow {
+		return true, 0
+	}
+
+	if d.repeats.entries == nil {
+		d.repeats.entries = make(map[collapseKey]*repeat)
+	}
+	if len(d.repeats.entries) >= maxRepeats {
+		for k, r := range d.repeats.entries {
+			if now.Sub(r.logged) >= d.CollapseWindow {
+				delete(d.repeats.entries, k)
+			}
+		}
+	}
+
+	r, ok := d.repeats.entries[key]
+	if !ok {
+		r = &repeat{}
+		d.repeats.entries[key] = r
+	}
+	held, r.logged, r.count = r.count, now, 0
+	return false, held
+}
+
+// countRepeat records a repeat that was held back
+func (d *DebugTransport) countRepeat(key collapseKey) {
+	d.repeats.mu.Lock()
+	defer d.repeats.mu.Unlock()
+
+	if r, ok := d.repeats.entries[key]; ok {
+		r.count++
+	}
+}
@@ -0,0 +1,150 @@
+// httpdbg/concurrency.go
+package httpdbg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrTooManyRequests is returned, wrapped with the host, when a request
+// can't get an in-flight slot and may not wait for one
+var ErrTooManyRequests = errors.New("too many requests in flight")
+
+// ConcurrencyLimitTransport caps the requests in flight, overall and per
+// host. A request holds its slot until its response body is closed or
+// read to the end.
+type ConcurrencyLimitTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// MaxInFlight caps all requests together; zero means no cap
+	MaxInFlight int
+	// MaxPerHost caps requests to each host; zero means no cap
+	MaxPerHost int
+	// MaxWaiting is how many requests may queue for a slot before further
+	// ones fail with ErrTooManyRequests; zero lets any number wait and a
+	// negative value fails as soon as no slot is free. Waiting respects
+	// the request's context.
+	MaxWaiting int
+
+	mu      sync.Mutex
+	global  chan struct{}
+	hosts   map[string]chan struct{}
+	waiting int32
+}
+
+// RoundTrip acquires slots for req, then sends it
+func (t *ConcurrencyLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	global, host := t.semaphores(req.URL.Host)
+	var held []chan struct{}
+	release := func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			<-held[i]
+		}
+	}
+
+	// The host slot comes first, so requests queued behind a busy host
+	// don't hold global slots other hosts could use
+	for _, sem := range []chan struct{}{host, global} {
+		if sem == nil {
+			continue
+		}
+		if err := t.acquire(req.Context(), sem, req.URL.Host); err != nil {
+			release()
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, err
+		}
+		held = append(held, sem)
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// acquire takes a slot from sem, queueing as MaxWaiting allows
+func (t *ConcurrencyLimitTransport) acquire(ctx context.Context, sem chan struct{}, host string) error {
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if t.MaxWaiting < 0 {
+		return fmt.Errorf("%w: %s", ErrTooManyRequests, host)
+	}
+	if n := atomic.AddInt32(&t.waiting, 1); t.MaxWaiting > 0 && int(n) > t.MaxWaiting {
+		atomic.AddInt32(&t.waiting, -1)
+		return fmt.Errorf("%w: %s", ErrTooManyRequests, host)
+	}
+	defer atomic.AddInt32(&t.waiting, -1)
+
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// semaphores returns the global and per-host semaphores; either is nil
+// when uncapped
+func (t *ConcurrencyLimitTransport) semaphores(host string) (chan struct{}, chan struct{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.global == nil && t.MaxInFlight > 0 {
+		t.global = make(chan struct{}, t.MaxInFlight)
+	}
+	if t.MaxPerHost <= 0 {
+		return t.global, nil
+	}
+
+	if t.hosts == nil {
+		t.hosts = make(map[string]chan struct{})
+	}
+	sem, ok := t.hosts[host]
+	if !ok {
+		sem = make(chan struct{}, t.MaxPerHost)
+		t.hosts[host] = sem
+	}
+	return t.global, sem
+}
+
+// releaseBody frees a request's slots once its body is done with
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Read releases the slots at the end of the body
+func (b *releaseBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.once.Do(b.release)
+	}
+	return n, err
+}
+
+// Close closes the body and releases the slots
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
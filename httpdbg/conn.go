@@ -0,0 +1,114 @@
+// httpdbg/conn.go
+package httpdbg
+
+import (
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// connUse records how a request got its connection, via httptrace
+type connUse struct {
+	mu sync.Mutex
+	// asked is when the request asked the pool for a connection, got when
+	// it got one
+	asked, got time.Time
+	reused     bool
+	// idle is how long a reused connection sat in the pool
+	idle          time.Duration
+	local, remote string
+}
+
+// clientTrace returns the hooks that fill in c
+func (c *connUse) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(string) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.asked = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.got = time.Now()
+			c.reused = info.Reused
+			if info.WasIdle {
+				c.idle = info.IdleTime
+			}
+			if info.Conn != nil {
+				c.local = info.Conn.LocalAddr().String()
+				c.remote = info.Conn.RemoteAddr().String()
+			}
+		},
+	}
+}
+
+// kind returns "reused" or "new" for the connection, or "none" if the
+// request never got one
+func (c *connUse) kind() string {
+	switch {
+	case c.got.IsZero():
+		return "none"
+	case c.reused:
+		return "reused"
+	}
+	return "new"
+}
+
+// wait returns how long the request waited for its connection: the dial
+// for a new one, or a pool wait when MaxConnsPerHost is reached
+func (c *connUse) wait() time.Duration {
+	if c.asked.IsZero() {
+		return 0
+	}
+	if c.got.IsZero() {
+		return time.Since(c.asked)
+	}
+	return c.got.Sub(c.asked)
+}
+
+// String describes the connection, e.g. "reused, idle 1.2s, waited 5µs,
+// 10.0.0.2:51234 -> 93.184.216.34:443", or returns "" if the request
+// never asked for one
+func (c *connUse) String() string {
+	if c == nil {
+		return ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.asked.IsZero() {
+		return ""
+	}
+	s := c.kind()
+	if c.idle > 0 {
+		s += fmt.Sprintf(", idle %s", c.idle.Round(time.Millisecond))
+	}
+	s += fmt.Sprintf(", waited %s", c.wait().Round(time.Microsecond))
+	if c.remote != "" {
+		s += fmt.Sprintf(", %s -> %s", c.local, c.remote)
+	}
+	return s
+}
+
+// fields returns the connection details as structured log fields
+func (c *connUse) fields() []Field {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.asked.IsZero() {
+		return nil
+	}
+	fields := []Field{{"conn", c.kind()}, {"conn_wait", c.wait()}}
+	if c.idle > 0 {
+		fields = append(fields, Field{"conn_idle", c.idle})
+	}
+	if c.remote != "" {
+		fields = append(fields, Field{"conn_local", c.local}, Field{"conn_remote", c.remote})
+	}
+	return fields
+}
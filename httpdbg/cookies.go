@@ -0,0 +1,111 @@
+// httpdbg/cookies.go
+package httpdbg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// PersistentJar is a cookie jar that can be saved to and restored from a
+// JSON file, for session-based APIs
+//
+//	jar, err := httpdbg.NewPersistentJar("session.json")
+//	client := httpdbg.NewClient(httpdbg.WithCookieJar(jar))
+//	...
+//	err = jar.Save()
+type PersistentJar struct {
+	path string
+	jar  *cookiejar.Jar
+
+	mu sync.Mutex
+	// entries holds every cookie set, by the origin that set it
+	entries map[string][]*http.Cookie
+}
+
+// NewPersistentJar returns a jar saved at path, loading the cookies
+// already there
+func NewPersistentJar(path string) (*PersistentJar, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	j := &PersistentJar{path: path, jar: jar, entries: make(map[string][]*http.Cookie)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var saved map[string][]*http.Cookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	for origin, cookies := range saved {
+		u, err := url.Parse(origin)
+		if err != nil {
+			continue
+		}
+		j.SetCookies(u, cookies)
+	}
+	return j, nil
+}
+
+// SetCookies stores cookies received from u
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.jar.SetCookies(u, cookies)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	origin := u.Scheme + "://" + u.Host
+	for _, c := range cookies {
+		j.entries[origin] = replaceCookie(j.entries[origin], c)
+	}
+}
+
+// Cookies returns the cookies to send to u
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return j.jar.Cookies(u)
+}
+
+// Save writes the unexpired cookies to the jar's file
+func (j *PersistentJar) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	saved := make(map[string][]*http.Cookie, len(j.entries))
+	for origin, cookies := range j.entries {
+		for _, c := range cookies {
+			if c.MaxAge < 0 || !c.Expires.IsZero() && c.Expires.Before(now) {
+				continue
+			}
+			saved[origin] = append(saved[origin], c)
+		}
+	}
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(j.path, data, 0o600)
+}
+
+// replaceCookie adds c to cookies, replacing one with the same name,
+// domain and path
+func replaceCookie(cookies []*http.Cookie, c *http.Cookie) []*http.Cookie {
+	for i, existing := range cookies {
+		if existing.Name == c.Name && existing.Domain == c.Domain && existing.Path == c.Path {
+			cookies[i] = c
+			return cookies
+		}
+	}
+	return append(cookies, c)
+}
@@ -0,0 +1,51 @@
+// httpdbg/curl.go
+package httpdbg
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// curlCommand renders req as an equivalent curl command. Headers, the URL
+// and JSON bodies pass through Redaction, so masked secrets need filling
+// in before the command is run.
+func (d *DebugTransport) curlCommand(req *http.Request, body []byte) string {
+	r := d.redaction(req)
+	cmd := "curl "
+	if req.Method != "" && req.Method != http.MethodGet {
+		cmd += "-X " + req.Method + " "
+	}
+	parts := []string{cmd + shellQuote(r.redactURL(req.URL))}
+
+	if req.Host != "" && req.Host != req.URL.Host {
+		parts = append(parts, "-H "+shellQuote("Host: "+req.Host))
+	}
+
+	headers := r.redactHeaders(req.Header)
+	keys := make([]string, 0, len(headers))
+	for k := range headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range headers[k] {
+			parts = append(parts, "-H "+shellQuote(k+": "+v))
+		}
+	}
+
+	if len(body) > 0 {
+		if isBinary(req.Header.Get("Content-Type"), body) {
+			parts = append(parts, "--data-binary @body.bin")
+		} else {
+			parts = append(parts, "--data-binary "+shellQuote(string(r.redactBody(body, r.graphQLVariableFields()...))))
+		}
+	}
+
+	return strings.Join(parts, " \\\n  ")
+}
+
+// shellQuote single-quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
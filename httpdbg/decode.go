@@ -0,0 +1,52 @@
+// httpdbg/decode.go
+package httpdbg
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// decodeBody undoes gzip or deflate Content-Encoding so the logged body is
+// readable. It works on a copy; the caller still receives the encoded bytes.
+// The body is returned unchanged if the encoding is unknown or invalid.
+func decodeBody(h http.Header, body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+
+	var r io.Reader
+	switch strings.ToLower(strings.TrimSpace(h.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return body
+		}
+		defer zr.Close()
+		r = zr
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw
+		// DEFLATE data, so fall back to that
+		zr, err := zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			fr := flate.NewReader(bytes.NewReader(body))
+			defer fr.Close()
+			r = fr
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	default:
+		return body
+	}
+
+	decoded, err := io.ReadAll(r)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
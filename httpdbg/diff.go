@@ -0,0 +1,193 @@
+// httpdbg/diff.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffIgnoredHeaders are the response headers Diff skips, as they differ
+// between any two responses
+var DiffIgnoredHeaders = []string{
+	"Date", "Age", "Expires", "Last-Modified", "Etag", "Set-Cookie", "Content-Length",
+	"X-Request-Id", "X-Amzn-Requestid", "X-Amz-Cf-Id", "Cf-Ray",
+}
+
+// maxDiffLines caps the text bodies Diff compares line by line
+const maxDiffLines = 2000
+
+// Diff describes how b's response differs from a's: status, headers
+// (other than DiffIgnoredHeaders) and body. JSON bodies are compared by
+// value, reported per path such as $.items[2].name; other bodies are
+// compared line by line. Diff returns "" when the responses match.
+func Diff(a, b *CapturedExchange) string {
+	var out strings.Builder
+
+	if a.Status != b.Status {
+		fmt.Fprintf(&out, "Status: %d -> %d\n", a.Status, b.Status)
+	}
+	diffHeaders(&out, a.ResponseHeader, b.ResponseHeader)
+
+	bodyA := decodeBody(a.ResponseHeader, a.ResponseBody)
+	bodyB := decodeBody(b.ResponseHeader, b.ResponseBody)
+	if !bytes.Equal(bodyA, bodyB) {
+		var body strings.Builder
+		var va, vb any
+		if json.Unmarshal(bodyA, &va) == nil && json.Unmarshal(bodyB, &vb) == nil {
+			diffJSON(&body, "$", va, vb)
+		} else {
+			diffLines(&body, string(bodyA), string(bodyB))
+		}
+		// JSON bodies can differ only in formatting
+		if body.Len() > 0 {
+			out.WriteString("Body:\n")
+			out.WriteString(body.String())
+		}
+	}
+	return out.String()
+}
+
+// diffHeaders reports headers added, removed or changed
+func diffHeaders(out *strings.Builder, a, b http.Header) {
+	names := make(map[string]bool)
+	for k := range a {
+		names[http.CanonicalHeaderKey(k)] = true
+	}
+	for k := range b {
+		names[http.CanonicalHeaderKey(k)] = true
+	}
+	for _, ignored := range DiffIgnoredHeaders {
+		delete(names, http.CanonicalHeaderKey(ignored))
+	}
+
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	for _, k := range sorted {
+		va, vb := a.Values(k), b.Values(k)
+		switch {
+		case len(va) == 0:
+			fmt.Fprintf(out, "Header %s: (missing) -> %q\n", k, strings.Join(vb, ", "))
+		case len(vb) == 0:
+			fmt.Fprintf(out, "Header %s: %q -> (missing)\n", k, strings.Join(va, ", "))
+		case !reflect.DeepEqual(va, vb):
+			fmt.Fprintf(out, "Header %s: %q -> %q\n", k, strings.Join(va, ", "), strings.Join(vb, ", "))
+		}
+	}
+}
+
+// diffJSON reports the paths at which two decoded JSON values differ
+func diffJSON(out *strings.Builder, path string, a, b any) {
+	switch va := a.(type) {
+	case map[string]any:
+		vb, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := make(map[string]bool)
+		for k := range va {
+			keys[k] = true
+		}
+		for k := range vb {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		for _, k := range sorted {
+			ea, inA := va[k]
+			eb, inB := vb[k]
+			p := path + "." + k
+			switch {
+			case !inA:
+				fmt.Fprintf(out, "  %s: (missing) -> %s\n", p, jsonText(eb))
+			case !inB:
+				fmt.Fprintf(out, "  %s: %s -> (missing)\n", p, jsonText(ea))
+			default:
+				diffJSON(out, p, ea, eb)
+			}
+		}
+		return
+	case []any:
+		vb, ok := b.([]any)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(va) || i < len(vb); i++ {
+			p := path + "[" + strconv.Itoa(i) + "]"
+			switch {
+			case i >= len(va):
+				fmt.Fprintf(out, "  %s: (missing) -> %s\n", p, jsonText(vb[i]))
+			case i >= len(vb):
+				fmt.Fprintf(out, "  %s: %s -> (missing)\n", p, jsonText(va[i]))
+			default:
+				diffJSON(out, p, va[i], vb[i])
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		fmt.Fprintf(out, "  %s: %s -> %s\n", path, jsonText(a), jsonText(b))
+	}
+}
+
+// jsonText renders a decoded JSON value compactly
+func jsonText(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// diffLines writes a unified-style line diff of two texts
+func diffLines(out *strings.Builder, a, b string) {
+	la, lb := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(la) > maxDiffLines || len(lb) > maxDiffLines {
+		fmt.Fprintf(out, "  bodies differ (%d -> %d bytes)\n", len(a), len(b))
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// la[i:] and lb[j:]
+	lcs := make([][]int, len(la)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(lb)+1)
+	}
+	for i := len(la) - 1; i >= 0; i-- {
+		for j := len(lb) - 1; j >= 0; j-- {
+			if la[i] == lb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(la) || j < len(lb) {
+		switch {
+		case i < len(la) && j < len(lb) && la[i] == lb[j]:
+			i, j = i+1, j+1
+		case i < len(la) && (j == len(lb) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(out, "  - %s\n", la[i])
+			i++
+		default:
+			fmt.Fprintf(out, "  + %s\n", lb[j])
+			j++
+		}
+	}
+}
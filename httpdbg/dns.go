@@ -0,0 +1,227 @@
+// httpdbg/dns.go
+package httpdbg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDNSTTL is how long CachingResolver keeps a lookup when TTL is zero
+const DefaultDNSTTL = time.Minute
+
+// CachingResolver dials connections through a cache of DNS lookups, so
+// chatty clients don't resolve the same names over and over, with static
+// overrides to send a host's requests to a specific backend. Plug it into
+// a transport as its DialContext, or use WithResolver:
+//
+//	r := &httpdbg.CachingResolver{Overrides: map[string]string{"api.example.com": "10.0.0.7"}}
+//	transport.DialContext = r.DialContext
+//
+// TLS still verifies the certificate against the host name in the URL.
+// The system resolver doesn't report record TTLs, so every lookup is kept
+// for TTL.
+type CachingResolver struct {
+	// Resolver does the lookups; defaults to net.DefaultResolver
+	Resolver *net.Resolver
+	// Dial opens the connections; defaults to a net.Dialer
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TTL is how long a lookup is cached; defaults to DefaultDNSTTL
+	TTL time.Duration
+	// NegativeTTL is how long a failed lookup is cached; zero retries
+	// failed lookups every time
+	NegativeTTL time.Duration
+	// Overrides maps host names to the IP address used in place of DNS,
+	// like an /etc/hosts entry
+	Overrides map[string]string
+	// Debug, if set, logs each resolution (host, addresses, whether it was
+	// cached or overridden, and how long the lookup took) through its
+	// Output or Logger
+	Debug *DebugTransport
+
+	mu    sync.Mutex
+	cache map[string]dnsEntry
+}
+
+// dnsEntry is a cached lookup
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// Sources of a resolution, as logged
+const (
+	dnsLookup   = "lookup"
+	dnsCached   = "cached"
+	dnsOverride = "override"
+)
+
+// DialContext resolves addr's host through the cache and dials its
+// addresses in turn until one connects
+func (r *CachingResolver) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return r.dial(ctx, network, addr)
+	}
+
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs = addrsFor(network, addrs)
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no suitable address found", Name: host}
+	}
+
+	var errs []error
+	for _, ip := range addrs {
+		conn, err := r.dial(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// LookupHost returns host's addresses from its override, the cache or a
+// fresh lookup
+func (r *CachingResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip, ok := r.Overrides[name]; ok {
+		addrs := []string{ip}
+		r.log(ctx, host, addrs, nil, dnsOverride, 0)
+		return addrs, nil
+	}
+
+	r.mu.Lock()
+	e, ok := r.cache[name]
+	r.mu.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		r.log(ctx, host, e.addrs, e.err, dnsCached, 0)
+		return e.addrs, e.err
+	}
+
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	start := time.Now()
+	addrs, err := resolver.LookupHost(ctx, name)
+	took := time.Since(start)
+	r.log(ctx, host, addrs, err, dnsLookup, took)
+
+	ttl := r.TTL
+	if ttl <= 0 {
+		ttl = DefaultDNSTTL
+	}
+	if err != nil {
+		// A cancelled lookup says nothing about the name
+		if r.NegativeTTL <= 0 || ctx.Err() != nil {
+			return nil, err
+		}
+		ttl = r.NegativeTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cache == nil {
+		r.cache = make(map[string]dnsEntry)
+	}
+	r.cache[name] = dnsEntry{addrs: addrs, err: err, expires: time.Now().Add(ttl)}
+	return addrs, err
+}
+
+// Flush empties the cache
+func (r *CachingResolver) Flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache = nil
+}
+
+// dial opens a connection to addr
+func (r *CachingResolver) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if r.Dial != nil {
+		return r.Dial(ctx, network, addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// log reports a resolution through Debug
+func (r *CachingResolver) log(ctx context.Context, host string, addrs []string, err error, source string, took time.Duration) {
+	d := r.Debug
+	if d == nil {
+		return
+	}
+
+	w := d.outputContext(ctx)
+	x := &exchange{w: w, logger: d.logger(w)}
+	if x.logger != nil {
+		fields := []Field{{"host", host}, {"dns_source", source}}
+		level := LevelDebug
+		if err != nil {
+			fields = append(fields, Field{"error", err.Error()})
+			level = LevelWarn
+		} else {
+			fields = append(fields, Field{"addrs", addrs})
+		}
+		if source == dnsLookup {
+			fields = append(fields, Field{"duration", took})
+		}
+		x.logger.Log(ctx, level, "dns resolution", fields...)
+		return
+	}
+
+	result := strings.Join(addrs, ", ")
+	if err != nil {
+		result = "error: " + err.Error()
+	}
+	if source == dnsLookup {
+		source = fmt.Sprintf("%s %s", source, took.Round(time.Microsecond))
+	}
+
+	w, done := d.entry(x)
+	defer done()
+	fmt.Fprintf(w, "DNS: %s -> %s (%s)\n", host, result, source)
+}
+
+// addrsFor keeps the addresses network can dial: IPv4 only for "tcp4",
+// IPv6 only for "tcp6"
+func addrsFor(network string, addrs []string) []string {
+	want4 := strings.HasSuffix(network, "4")
+	want6 := strings.HasSuffix(network, "6")
+	if !want4 && !want6 {
+		return addrs
+	}
+
+	var kept []string
+	for _, a := range addrs {
+		ip := net.ParseIP(a)
+		if ip == nil {
+			continue
+		}
+		if is4 := ip.To4() != nil; is4 == want4 {
+			kept = append(kept, a)
+		}
+	}
+	return kept
+}
+
+// WithResolver dials the client's connections through r
+func WithResolver(r *CachingResolver) Option {
+	return func(c *clientConfig) {
+		c.transport().DialContext = r.DialContext
+	}
+}
@@ -0,0 +1,207 @@
+// httpdbg/errors.go
+package httpdbg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sync"
+)
+
+// DNSError is returned by DebugTransport when the host name didn't resolve
+type DNSError struct {
+	Host string
+	Err  error
+}
+
+func (e *DNSError) Error() string   { return fmt.Sprintf("dns lookup for %s failed: %v", e.Host, e.Err) }
+func (e *DNSError) Unwrap() error   { return e.Err }
+func (e *DNSError) Timeout() bool   { return isTimeout(e.Err) }
+func (e *DNSError) errKind() string { return "dns" }
+
+// ConnectTimeout is returned by DebugTransport when connecting to the
+// host timed out
+type ConnectTimeout struct {
+	Host string
+	Err  error
+}
+
+func (e *ConnectTimeout) Error() string {
+	return fmt.Sprintf("connecting to %s timed out: %v", e.Host, e.Err)
+}
+func (e *ConnectTimeout) Unwrap() error   { return e.Err }
+func (e *ConnectTimeout) Timeout() bool   { return true }
+func (e *ConnectTimeout) errKind() string { return "connect_timeout" }
+
+// TLSHandshakeError is returned by DebugTransport when the TLS handshake
+// with the host failed, e.g. on an untrusted certificate
+type TLSHandshakeError struct {
+	Host string
+	Err  error
+}
+
+func (e *TLSHandshakeError) Error() string {
+	return fmt.Sprintf("tls handshake with %s failed: %v", e.Host, e.Err)
+}
+func (e *TLSHandshakeError) Unwrap() error   { return e.Err }
+func (e *TLSHandshakeError) Timeout() bool   { return isTimeout(e.Err) }
+func (e *TLSHandshakeError) errKind() string { return "tls_handshake" }
+
+// ResponseTimeout is returned by DebugTransport when the request was
+// sent but the response didn't arrive in time
+type ResponseTimeout struct {
+	Host string
+	Err  error
+}
+
+func (e *ResponseTimeout) Error() string {
+	return fmt.Sprintf("waiting for response from %s timed out: %v", e.Host, e.Err)
+}
+func (e *ResponseTimeout) Unwrap() error   { return e.Err }
+func (e *ResponseTimeout) Timeout() bool   { return true }
+func (e *ResponseTimeout) errKind() string { return "response_timeout" }
+
+// ProxyError is returned by DebugTransport when the request couldn't get
+// through its proxy: the proxy was unreachable or refused the CONNECT
+type ProxyError struct {
+	Host string
+	// Proxy is the proxy URL, with any password masked
+	Proxy string
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s failed for %s: %v", e.Proxy, e.Host, e.Err)
+}
+func (e *ProxyError) Unwrap() error   { return e.Err }
+func (e *ProxyError) Timeout() bool   { return isTimeout(e.Err) }
+func (e *ProxyError) errKind() string { return "proxy" }
+
+// isTimeout reports whether err is a timeout
+func isTimeout(err error) bool {
+	var ne net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout())
+}
+
+// errorKind returns the kind of a classified error, or ""
+func errorKind(err error) string {
+	if k, ok := err.(interface{ errKind() string }); ok {
+		return k.errKind()
+	}
+	return ""
+}
+
+// Phases of a request, as reported for failures
+const (
+	phaseDNS      = "dns"
+	phaseConnect  = "connect"
+	phaseTLS      = "tls"
+	phaseRequest  = "request"
+	phaseResponse = "response"
+)
+
+// phaseTracker follows how far a request got, via httptrace, so a
+// failure can be placed
+type phaseTracker struct {
+	mu sync.Mutex
+	// dns, connect and tls count the lookups, connects and handshakes
+	// under way; failed is the phase of the last one to fail
+	dns, connect, tls int
+	failed            string
+	gotConn, wrote    bool
+}
+
+// clientTrace returns the hooks that fill in t
+func (t *phaseTracker) clientTrace() *httptrace.ClientTrace {
+	start := func(count *int) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*count++
+	}
+	done := func(count *int, phase string, err error) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*count--
+		if err != nil {
+			t.failed = phase
+		}
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { start(&t.dns) },
+		DNSDone:           func(info httptrace.DNSDoneInfo) { done(&t.dns, phaseDNS, info.Err) },
+		ConnectStart:      func(string, string) { start(&t.connect) },
+		ConnectDone:       func(_, _ string, err error) { done(&t.connect, phaseConnect, err) },
+		TLSHandshakeStart: func() { start(&t.tls) },
+		TLSHandshakeDone:  func(_ tls.ConnectionState, err error) { done(&t.tls, phaseTLS, err) },
+		GotConn: func(httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.gotConn = true
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.wrote = true
+		},
+	}
+}
+
+// phase returns the phase the request was in, or "" if it failed before
+// any started
+func (t *phaseTracker) phase() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case t.gotConn && t.wrote:
+		return phaseResponse
+	case t.gotConn:
+		return phaseRequest
+	case t.dns > 0:
+		return phaseDNS
+	case t.tls > 0:
+		return phaseTLS
+	case t.connect > 0:
+		return phaseConnect
+	}
+	return t.failed
+}
+
+// classifyError wraps a RoundTrip failure in the typed error matching
+// where it happened; errors that fit none are returned as they are
+func classifyError(x *exchange, phase string, err error) error {
+	// A request the caller gave up on didn't fail by itself
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	host := x.req.URL.Host
+
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCert x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	tlsFailure := errors.As(err, &certErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidCert) || errors.As(err, &recordErr)
+
+	var opErr *net.OpError
+	if proxy := x.proxy.String(); proxy != "" && (x.proxy.refused() || (errors.As(err, &opErr) && opErr.Op == "proxyconnect")) {
+		return &ProxyError{Host: host, Proxy: proxy, Err: err}
+	}
+
+	switch {
+	case errors.As(err, &dnsErr) || phase == phaseDNS:
+		return &DNSError{Host: host, Err: err}
+	case tlsFailure || phase == phaseTLS:
+		return &TLSHandshakeError{Host: host, Err: err}
+	case phase == phaseConnect && isTimeout(err):
+		return &ConnectTimeout{Host: host, Err: err}
+	case phase == phaseResponse && isTimeout(err):
+		return &ResponseTimeout{Host: host, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// httpdbg/exchange.go
+package httpdbg
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"time"
+)
+
+// exchange carries the state of one round trip through DebugTransport
+type exchange struct {
+	// w is the text output and logger the structured one, if any
+	w      io.Writer
+	logger Logger
+	// id is the request ID; empty unless RequestID is set
+	id string
+	// seq numbers the exchanges logged by a DebugTransport, from 1
+	seq uint64
+	// phase tracks how far the request got, to place failures
+	phase *phaseTracker
+	// held is the request block GroupExchanges holds back
+	held *bytes.Buffer
+	// redaction is the Redaction that applies to the request's host
+	redaction *Redaction
+	req       *http.Request
+	// reqBuf holds the request body read up front, or reqTee what has
+	// been sent of a streamed one
+	reqBuf *pooledBuffer
+	reqTee *limitedBuffer
+	// reqSkipped and respSkipped are set for bodies left uncaptured
+	// because MaxCaptureMemory was reached
+	reqSkipped  bool
+	respSkipped bool
+	start       time.Time
+	timings     *phaseTimings
+	// proxy records the proxy the request went through, if any
+	proxy *proxyUse
+	// conn records how the request got its connection, with ConnInfo
+	conn *connUse
+	// repeated is the number of identical requests held back by
+	// CollapseWindow since this one was last logged
+	repeated int
+	// graphQL holds the operations of a GraphQL request, if it is one
+	graphQL []graphQLOperation
+}
+
+// release lets go of the request body copy once the exchange is logged
+func (x *exchange) release() {
+	x.reqBuf.release()
+	x.reqTee.free()
+}
+
+// requestBody returns the logged part of the request body
+func (x *exchange) requestBody() []byte {
+	if x.reqTee != nil {
+		return x.reqTee.Bytes()
+	}
+	return x.reqBuf.Bytes()
+}
+
+// requestSize returns the size of the request body: what has been sent
+// of a streamed one, or its declared length when only part was copied
+func (x *exchange) requestSize() int64 {
+	if x.reqTee != nil {
+		return x.reqTee.Total()
+	}
+	n := int64(len(x.reqBuf.Bytes()))
+	if x.req != nil && x.req.ContentLength > n {
+		return x.req.ContentLength
+	}
+	return n
+}
+
+// redaction returns the Redaction for req's host
+func (d *DebugTransport) redaction(req *http.Request) *Redaction {
+	return d.hostRedaction(req.URL.Host)
+}
+
+// hostRedaction returns the Redaction for host (as in URL.Host): its
+// HostRedaction entry, or Redaction with every matching profile applied
+func (d *DebugTransport) hostRedaction(host string) *Redaction {
+	if r, ok := d.HostRedaction[host]; ok {
+		return &r
+	}
+
+	r := &d.Redaction
+	for i := range d.RedactionProfiles {
+		if p := &d.RedactionProfiles[i]; p.matches(host) {
+			merged := r.merge(p)
+			r = &merged
+		}
+	}
+	return r
+}
+
+// requestIDHeader returns the header carrying request IDs
+func (d *DebugTransport) requestIDHeader() string {
+	if d.RequestIDHeader != "" {
+		return d.RequestIDHeader
+	}
+	return "X-Request-ID"
+}
+
+// ensureRequestID returns the request's ID, generating one if it has none.
+// The header is set on a copy so the caller's request is left untouched.
+func (d *DebugTransport) ensureRequestID(req *http.Request) (*http.Request, string) {
+	header := d.requestIDHeader()
+	if id := req.Header.Get(header); id != "" {
+		return req, id
+	}
+
+	id := newRequestID()
+	req = req.Clone(req.Context())
+	req.Header.Set(header, id)
+	return req, id
+}
+
+// newRequestID returns a random 128-bit ID in hex
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,129 @@
+// httpdbg/fault.go
+package httpdbg
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Fault is a failure FaultTransport injects into matching requests
+type Fault struct {
+	// Match selects the requests affected; the zero Rule matches all
+	Match Rule
+	// Rate is the probability, from 0 to 1, that the fault hits a
+	// matching request
+	Rate float64
+	// Latency delays the request before it is sent
+	Latency time.Duration
+	// Drop fails the request with a connection reset instead of sending it
+	Drop bool
+	// Status answers with this status code instead of sending the request
+	Status int
+	// Malformed cuts the real response body short, so reading it fails
+	// with io.ErrUnexpectedEOF
+	Malformed bool
+}
+
+// FaultTransport injects latency, dropped connections, error statuses and
+// broken bodies, for exercising retry, breaker and timeout handling.
+// Faults are checked in order and each one hitting a request is applied.
+type FaultTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	Faults    []Fault
+}
+
+// RoundTrip sends req, applying the faults that hit it
+func (t *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var malformed bool
+	for _, f := range t.Faults {
+		if !f.Match.Matches(req) || rand.Float64() >= f.Rate {
+			continue
+		}
+
+		if f.Latency > 0 {
+			timer := time.NewTimer(f.Latency)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				closeBody(req)
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+		if f.Drop {
+			closeBody(req)
+			return nil, &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+		}
+		if f.Status != 0 {
+			closeBody(req)
+			return faultResponse(req, f.Status), nil
+		}
+		malformed = malformed || f.Malformed
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil || !malformed {
+		return resp, err
+	}
+	resp.Body = &truncatedBody{ReadCloser: resp.Body, remaining: 16}
+	return resp, nil
+}
+
+// closeBody closes a request body that won't be sent
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// faultResponse builds a synthetic response with the given status
+func faultResponse(req *http.Request, status int) *http.Response {
+	body := fmt.Sprintf("injected fault: %d %s\n", status, http.StatusText(status))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// truncatedBody returns the first bytes of a body and then fails as if
+// the connection had dropped
+type truncatedBody struct {
+	io.ReadCloser
+	remaining int
+}
+
+// Read serves the remaining allowance, then io.ErrUnexpectedEOF
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= n
+	if err == io.EOF {
+		// The body was shorter than the cut; break it anyway
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
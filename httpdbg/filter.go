@@ -0,0 +1,68 @@
+// httpdbg/filter.go
+package httpdbg
+
+import (
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rule matches requests by host, path and method. Empty criteria match
+// anything; a rule matches when all of its set criteria do.
+type Rule struct {
+	// Host is a glob matched against the host name without port, e.g.
+	// "*.example.com"
+	Host string
+	// Path matches the URL path
+	Path *regexp.Regexp
+	// Methods lists the HTTP methods matched (case-insensitive)
+	Methods []string
+}
+
+// Filter narrows down which requests DebugTransport logs. A request is
+// logged when it matches at least one Include rule (or Include is empty)
+// and no Exclude rule. Requests that aren't logged pass straight through.
+type Filter struct {
+	Include []Rule
+	Exclude []Rule
+}
+
+// Matches reports whether the rule matches req
+func (r Rule) Matches(req *http.Request) bool {
+	if r.Host != "" {
+		if ok, _ := path.Match(strings.ToLower(r.Host), strings.ToLower(req.URL.Hostname())); !ok {
+			return false
+		}
+	}
+	if r.Path != nil && !r.Path.MatchString(req.URL.Path) {
+		return false
+	}
+	if len(r.Methods) > 0 {
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, req.Method) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Allows reports whether req should be logged
+func (f *Filter) Allows(req *http.Request) bool {
+	for _, r := range f.Exclude {
+		if r.Matches(req) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, r := range f.Include {
+		if r.Matches(req) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,311 @@
+// httpdbg/graphql.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// graphQLOperation is one operation of a GraphQL request, with its
+// variables already redacted
+type graphQLOperation struct {
+	// Type is query, mutation or subscription; Name is empty for
+	// anonymous operations
+	Type      string
+	Name      string
+	Query     string
+	Variables json.RawMessage
+}
+
+// String names the operation, e.g. "query GetUser"
+func (o graphQLOperation) String() string {
+	typ := o.Type
+	if typ == "" {
+		typ = "operation"
+	}
+	if o.Name == "" {
+		return typ + " (anonymous)"
+	}
+	return typ + " " + o.Name
+}
+
+// graphQLRequestBody is the JSON body of a GraphQL request
+type graphQLRequestBody struct {
+	Query         string          `json:"query"`
+	OperationName string          `json:"operationName"`
+	Variables     json.RawMessage `json:"variables"`
+}
+
+// parseGraphQL extracts the operations of a GraphQL request: a GET with a
+// query parameter, a JSON body (batched or not), or an application/graphql
+// body. It returns nil for anything else. Variables are masked by r.
+func parseGraphQL(req *http.Request, body []byte, r *Redaction) []graphQLOperation {
+	if req.Method == http.MethodGet {
+		q := req.URL.Query()
+		query := q.Get("query")
+		if query == "" {
+			return nil
+		}
+		var vars json.RawMessage
+		if v := q.Get("variables"); v != "" {
+			vars = r.redactBody([]byte(v), r.GraphQLVariables...)
+		}
+		return []graphQLOperation{newGraphQLOperation(query, q.Get("operationName"), vars)}
+	}
+
+	body = decodeBody(req.Header, body)
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/graphql" {
+		return []graphQLOperation{newGraphQLOperation(string(body), "", nil)}
+	}
+	if !bytes.Contains(body, []byte(`"query"`)) {
+		return nil
+	}
+
+	// Masking the whole body lets the variable paths be rooted at each
+	// operation's variables, batched or not
+	body = r.redactBody(body, r.graphQLVariableFields()...)
+
+	var batch []graphQLRequestBody
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if json.Unmarshal(trimmed, &batch) != nil {
+			return nil
+		}
+	} else {
+		var single graphQLRequestBody
+		if json.Unmarshal(trimmed, &single) != nil {
+			return nil
+		}
+		batch = append(batch, single)
+	}
+
+	var ops []graphQLOperation
+	for _, b := range batch {
+		if b.Query == "" {
+			continue
+		}
+		ops = append(ops, newGraphQLOperation(b.Query, b.OperationName, b.Variables))
+	}
+	return ops
+}
+
+// graphQLVariableFields roots GraphQLVariables at a request's variables,
+// for masking a whole request body
+func (r *Redaction) graphQLVariableFields() []string {
+	fields := make([]string, len(r.GraphQLVariables))
+	for i, f := range r.GraphQLVariables {
+		fields[i] = "variables." + f
+	}
+	return fields
+}
+
+// newGraphQLOperation describes the operation named name in query, or
+// its first operation when name is empty
+func newGraphQLOperation(query, name string, vars json.RawMessage) graphQLOperation {
+	op := graphQLOperation{Name: name, Query: strings.TrimSpace(query)}
+	if len(vars) > 0 && string(vars) != "null" {
+		op.Variables = vars
+	}
+	for _, def := range graphQLDefinitions(query) {
+		if name == "" || def.Name == name {
+			op.Type, op.Name = def.Type, def.Name
+			break
+		}
+	}
+	return op
+}
+
+// graphQLDefinitions lists the operations defined in a GraphQL document.
+// It only tokenizes what it needs: top-level keywords and names, with
+// strings, comments and nesting skipped.
+func graphQLDefinitions(query string) []graphQLOperation {
+	var defs []graphQLOperation
+	var depth, parens int
+	// pending is an operation keyword still waiting for its name; inDef is
+	// set from a definition's name to its selection set
+	var pending string
+	var inDef bool
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			if end < 0 {
+				return defs
+			}
+			i += end + 6
+		case c == '"':
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case c == '(' || c == '@':
+			// Variable definitions or directives: the operation is anonymous
+			if depth == 0 && pending != "" {
+				defs = append(defs, graphQLOperation{Type: pending})
+				pending, inDef = "", true
+			}
+			if c == '(' {
+				parens++
+			}
+			i++
+		case c == ')':
+			parens--
+			i++
+		case c == '{':
+			if depth == 0 && parens == 0 {
+				if pending != "" {
+					defs = append(defs, graphQLOperation{Type: pending})
+				} else if !inDef {
+					// The shorthand "{ ... }" is an anonymous query
+					defs = append(defs, graphQLOperation{Type: "query"})
+				}
+				pending, inDef = "", false
+			}
+			depth++
+			i++
+		case c == '}':
+			depth--
+			i++
+		case c == '_' || (c|0x20 >= 'a' && c|0x20 <= 'z'):
+			start := i
+			for i < len(query) && (query[i] == '_' || (query[i]|0x20 >= 'a' && query[i]|0x20 <= 'z') || (query[i] >= '0' && query[i] <= '9')) {
+				i++
+			}
+			if depth > 0 || parens > 0 {
+				continue
+			}
+			word := query[start:i]
+			switch {
+			case pending != "":
+				defs = append(defs, graphQLOperation{Type: pending, Name: word})
+				pending, inDef = "", true
+			case inDef:
+			case word == "query" || word == "mutation" || word == "subscription":
+				pending = word
+			case word == "fragment":
+				inDef = true
+			}
+		default:
+			i++
+		}
+	}
+	return defs
+}
+
+// graphQLErrors summarizes the errors of a GraphQL response, one line
+// each with the error's path and code when given
+func graphQLErrors(h http.Header, body []byte) []string {
+	type gqlError struct {
+		Message    string `json:"message"`
+		Path       []any  `json:"path"`
+		Extensions struct {
+			Code any `json:"code"`
+		} `json:"extensions"`
+	}
+	type gqlResponse struct {
+		Errors []gqlError `json:"errors"`
+	}
+
+	body = bytes.TrimSpace(decodeBody(h, body))
+	if !bytes.Contains(body, []byte(`"errors"`)) {
+		return nil
+	}
+	var batch []gqlResponse
+	if len(body) > 0 && body[0] == '[' {
+		if json.Unmarshal(body, &batch) != nil {
+			return nil
+		}
+	} else {
+		var single gqlResponse
+		if json.Unmarshal(body, &single) != nil {
+			return nil
+		}
+		batch = append(batch, single)
+	}
+
+	var summary []string
+	for _, resp := range batch {
+		for _, e := range resp.Errors {
+			var details []string
+			if len(e.Path) > 0 {
+				path := make([]string, len(e.Path))
+				for i, p := range e.Path {
+					path[i] = fmt.Sprint(p)
+				}
+				details = append(details, "path: "+strings.Join(path, "."))
+			}
+			if e.Extensions.Code != nil {
+				details = append(details, fmt.Sprintf("code: %v", e.Extensions.Code))
+			}
+			line := e.Message
+			if len(details) > 0 {
+				line += " (" + strings.Join(details, ", ") + ")"
+			}
+			summary = append(summary, line)
+		}
+	}
+	return summary
+}
+
+// graphQLBody renders the operations of a GraphQL request in place of its
+// JSON body: each query as written, then its variables
+func (d *DebugTransport) graphQLBody(ops []graphQLOperation) string {
+	var sb strings.Builder
+	for i, op := range ops {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		query := op.Query
+		if limit := d.bodyLimit(); limit >= 0 && len(query) > limit {
+			query = fmt.Sprintf("%s... (%d bytes truncated)", query[:limit], len(query)-limit)
+		}
+		sb.WriteString(query)
+		if op.Variables != nil {
+			sb.WriteString("\nVariables: ")
+			sb.Write(op.Variables)
+		}
+	}
+	return sb.String()
+}
+
+// graphQLFields returns the structured fields of a GraphQL request
+func graphQLFields(ops []graphQLOperation) []Field {
+	names := make([]string, len(ops))
+	queries := make([]string, len(ops))
+	vars := make([]string, len(ops))
+	var hasVars bool
+	for i, op := range ops {
+		names[i] = op.String()
+		queries[i] = op.Query
+		vars[i] = "null"
+		if op.Variables != nil {
+			vars[i], hasVars = string(op.Variables), true
+		}
+	}
+
+	fields := []Field{
+		{"graphql_operation", strings.Join(names, ", ")},
+		{"graphql_query", strings.Join(queries, "\n\n")},
+	}
+	switch {
+	case !hasVars:
+	case len(ops) == 1:
+		fields = append(fields, Field{"graphql_variables", vars[0]})
+	default:
+		// Batched variables line up with the operations
+		fields = append(fields, Field{"graphql_variables", "[" + strings.Join(vars, ",") + "]"})
+	}
+	return fields
+}
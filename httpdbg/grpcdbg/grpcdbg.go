@@ -0,0 +1,278 @@
+// httpdbg/grpcdbg/grpcdbg.go
+package grpcdbg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"your/path/to/httpdbg"
+)
+
+// Interceptor logs gRPC client calls the way httpdbg.DebugTransport logs
+// HTTP exchanges: method, metadata, message sizes, status and duration,
+// with the same redaction and loggers:
+//
+//	dbg := &grpcdbg.Interceptor{}
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithUnaryInterceptor(dbg.Unary()),
+//		grpc.WithStreamInterceptor(dbg.Stream()),
+//	)
+//
+// Each call is logged once it completes; streams when they end.
+type Interceptor struct {
+	// Output receives the text logs; defaults to os.Stdout
+	Output io.Writer
+	// Logger, when set, receives one structured entry per call instead
+	Logger httpdbg.Logger
+	// Redaction masks metadata as it does headers, and message fields as
+	// it does JSON body fields
+	Redaction httpdbg.Redaction
+	// Messages logs unary request and response messages as JSON, truncated
+	// to MaxMessageLog bytes; otherwise only their sizes are logged
+	Messages bool
+	// MaxMessageLog caps each logged message; defaults to
+	// httpdbg.DefaultMaxBodyLog
+	MaxMessageLog int
+
+	mu sync.Mutex
+}
+
+// call is what is known about one RPC
+type call struct {
+	method   string
+	md       metadata.MD
+	header   metadata.MD
+	trailer  metadata.MD
+	sent     int
+	received int
+	sentSize int
+	recvSize int
+	request  proto.Message
+	response proto.Message
+	start    time.Time
+	err      error
+}
+
+// Unary returns the interceptor for unary calls
+func (i *Interceptor) Unary() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		c := &call{method: method, start: time.Now()}
+		c.md, _ = metadata.FromOutgoingContext(ctx)
+		opts = append(opts, grpc.Header(&c.header), grpc.Trailer(&c.trailer))
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		c.err = err
+		if m, ok := req.(proto.Message); ok {
+			c.sent, c.sentSize, c.request = 1, proto.Size(m), m
+		}
+		if m, ok := reply.(proto.Message); ok && err == nil {
+			c.received, c.recvSize, c.response = 1, proto.Size(m), m
+		}
+		i.log(ctx, c)
+		return err
+	}
+}
+
+// Stream returns the interceptor for streaming calls
+func (i *Interceptor) Stream() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		c := &call{method: method, start: time.Now()}
+		c.md, _ = metadata.FromOutgoingContext(ctx)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			c.err = err
+			i.log(ctx, c)
+			return nil, err
+		}
+		return &stream{ClientStream: cs, i: i, ctx: ctx, c: c}, nil
+	}
+}
+
+// stream counts the messages of a streaming call and logs it at the end
+type stream struct {
+	grpc.ClientStream
+	i    *Interceptor
+	ctx  context.Context
+	c    *call
+	mu   sync.Mutex
+	once sync.Once
+}
+
+func (s *stream) SendMsg(m any) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.mu.Lock()
+		s.c.sent++
+		if pm, ok := m.(proto.Message); ok {
+			s.c.sentSize += proto.Size(pm)
+		}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+func (s *stream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.finish(err)
+		return err
+	}
+	s.mu.Lock()
+	s.c.received++
+	if pm, ok := m.(proto.Message); ok {
+		s.c.recvSize += proto.Size(pm)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// finish logs the stream once it has ended; io.EOF is a clean end
+func (s *stream) finish(err error) {
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.c.err = err
+		s.c.header, _ = s.ClientStream.Header()
+		s.c.trailer = s.ClientStream.Trailer()
+		s.i.log(s.ctx, s.c)
+	})
+}
+
+// log writes the entry for a completed call
+func (i *Interceptor) log(ctx context.Context, c *call) {
+	st := status.Convert(c.err)
+	duration := time.Since(c.start)
+
+	if i.Logger != nil {
+		fields := []httpdbg.Field{
+			{Key: "method", Value: c.method},
+			{Key: "duration", Value: duration},
+			{Key: "code", Value: st.Code().String()},
+			{Key: "metadata", Value: i.metadataMap(c.md)},
+			{Key: "messages_sent", Value: c.sent},
+			{Key: "messages_received", Value: c.received},
+			{Key: "request_size", Value: c.sentSize},
+			{Key: "response_size", Value: c.recvSize},
+		}
+		if len(c.header) > 0 {
+			fields = append(fields, httpdbg.Field{Key: "response_metadata", Value: i.metadataMap(c.header)})
+		}
+		if c.err != nil {
+			fields = append(fields, httpdbg.Field{Key: "error", Value: st.Message()})
+		}
+		if i.Messages {
+			if c.request != nil {
+				fields = append(fields, httpdbg.Field{Key: "request", Value: i.message(c.request)})
+			}
+			if c.response != nil {
+				fields = append(fields, httpdbg.Field{Key: "response", Value: i.message(c.response)})
+			}
+		}
+		i.Logger.Log(ctx, level(st.Code()), "grpc call", fields...)
+		return
+	}
+
+	w := i.Output
+	if w == nil {
+		w = os.Stdout
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	fmt.Fprintln(w, "======= GRPC CALL =======")
+	fmt.Fprintf(w, "Method: %s\n", c.method)
+	fmt.Fprintf(w, "Status: %s", st.Code())
+	if c.err != nil {
+		fmt.Fprintf(w, " (%s)", st.Message())
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Duration: %s\n", duration.Round(time.Microsecond))
+	fmt.Fprintf(w, "Sent: %d messages, %d bytes\n", c.sent, c.sentSize)
+	fmt.Fprintf(w, "Received: %d messages, %d bytes\n", c.received, c.recvSize)
+	if len(c.md) > 0 {
+		fmt.Fprintln(w, "\nMetadata:")
+		writeMetadata(w, i.Redaction.Header(http.Header(c.md)))
+	}
+	if len(c.header) > 0 {
+		fmt.Fprintln(w, "\nResponse metadata:")
+		writeMetadata(w, i.Redaction.Header(http.Header(c.header)))
+	}
+	if i.Messages && c.request != nil {
+		fmt.Fprintf(w, "\nRequest:\n%s\n", i.message(c.request))
+	}
+	if i.Messages && c.response != nil {
+		fmt.Fprintf(w, "\nResponse:\n%s\n", i.message(c.response))
+	}
+	fmt.Fprintln(w, "=========================")
+}
+
+// message renders a message as redacted, truncated JSON
+func (i *Interceptor) message(m proto.Message) string {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return fmt.Sprintf("[%s: %v]", proto.MessageName(m), err)
+	}
+	b = i.Redaction.Body(b)
+
+	limit := i.MaxMessageLog
+	if limit == 0 {
+		limit = httpdbg.DefaultMaxBodyLog
+	}
+	if limit > 0 && len(b) > limit {
+		return fmt.Sprintf("%s... (%d bytes truncated)", b[:limit], len(b)-limit)
+	}
+	return string(b)
+}
+
+// metadataMap flattens redacted metadata for structured logs
+func (i *Interceptor) metadataMap(md metadata.MD) map[string]string {
+	m := make(map[string]string, len(md))
+	for k, v := range i.Redaction.Header(http.Header(md)) {
+		m[k] = fmt.Sprint(v)
+	}
+	return m
+}
+
+// writeMetadata prints metadata sorted by key. Keys keep their gRPC
+// lower case; the redaction lookup is case-insensitive.
+func writeMetadata(w io.Writer, md http.Header) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s: %v\n", k, md[k])
+	}
+}
+
+// level maps a status code to a log level, as HTTP statuses are: client
+// mistakes warn, server failures are errors
+func level(code codes.Code) httpdbg.Level {
+	switch code {
+	case codes.OK:
+		return httpdbg.LevelDebug
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented, codes.DeadlineExceeded:
+		return httpdbg.LevelError
+	default:
+		return httpdbg.LevelWarn
+	}
+}
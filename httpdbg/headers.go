@@ -0,0 +1,50 @@
+// httpdbg/headers.go
+package httpdbg
+
+import (
+	"encoding/base64"
+	"net/http"
+)
+
+// HeaderTransport adds a fixed set of headers to every request, such as
+// credentials, a User-Agent or API keys. Headers the request already has
+// are left alone, so call sites can still override them.
+//
+//	client := &http.Client{Transport: &httpdbg.HeaderTransport{
+//		Transport: &httpdbg.DebugTransport{},
+//		Headers: http.Header{
+//			"Authorization": {httpdbg.BearerAuth(token)},
+//			"User-Agent":    {"reports-job/1.0"},
+//		},
+//	}}
+type HeaderTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	Headers   http.Header
+}
+
+// RoundTrip sends a copy of req with the missing headers added
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	req = req.Clone(req.Context())
+	for k, v := range t.Headers {
+		if _, ok := req.Header[http.CanonicalHeaderKey(k)]; !ok {
+			req.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+		}
+	}
+	return transport.RoundTrip(req)
+}
+
+// BasicAuth returns an Authorization value for HTTP Basic authentication
+func BasicAuth(username, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+}
+
+// BearerAuth returns an Authorization value for a bearer token
+func BearerAuth(token string) string {
+	return "Bearer " + token
+}
@@ -0,0 +1,39 @@
+// httpdbg/hooks.go
+package httpdbg
+
+import (
+	"net/http"
+	"time"
+)
+
+// hooks holds the callbacks registered on a DebugTransport
+type hooks struct {
+	onRequest  []func(*http.Request)
+	onResponse []func(*http.Response, time.Duration, error)
+	onCapture  []func(*CapturedExchange)
+}
+
+// OnRequest registers fn to run on every request before it is logged and
+// sent, whether or not it is logged. fn gets a copy of the request, which
+// it may modify, e.g. to add headers.
+func (d *DebugTransport) OnRequest(fn func(*http.Request)) {
+	d.hookMu.Lock()
+	defer d.hookMu.Unlock()
+	d.hooks.onRequest = append(d.hooks.onRequest, fn)
+}
+
+// OnResponse registers fn to run once every request completes, with the
+// response or error and how long the exchange took. The response is the
+// one returned to the caller, so fn must not read or close its body.
+func (d *DebugTransport) OnResponse(fn func(*http.Response, time.Duration, error)) {
+	d.hookMu.Lock()
+	defer d.hookMu.Unlock()
+	d.hooks.onResponse = append(d.hooks.onResponse, fn)
+}
+
+// registeredHooks returns the callbacks registered so far
+func (d *DebugTransport) registeredHooks() hooks {
+	d.hookMu.RLock()
+	defer d.hookMu.RUnlock()
+	return d.hooks
+}
@@ -0,0 +1,107 @@
+// httpdbg/hosts.go
+package httpdbg
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HostConfig holds the settings for requests to one host. Zero fields
+// leave that behaviour off.
+type HostConfig struct {
+	// Timeout and HeaderTimeout bound each request, as in TimeoutTransport;
+	// Timeout covers all retries
+	Timeout       time.Duration
+	HeaderTimeout time.Duration
+	// Retry retries the host's failed requests. Its Transport field is set
+	// by HostTransport, so give each host its own RetryTransport.
+	Retry *RetryTransport
+	// RateLimit caps the host's request rate
+	RateLimit Rate
+	// Headers are added to requests that don't already set them
+	Headers http.Header
+	// Redaction replaces DebugTransport's Redaction for the host. Only
+	// WithHostConfig applies it; with HostTransport, set
+	// DebugTransport.HostRedaction instead.
+	Redaction *Redaction
+}
+
+// HostTransport sends each request through a stack of transports set up
+// for its host, so one client can talk to several APIs with appropriate
+// settings. Requests to other hosts go straight to Transport.
+type HostTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Hosts holds the configuration for specific hosts (as in URL.Host)
+	Hosts map[string]HostConfig
+
+	mu     sync.Mutex
+	stacks map[string]http.RoundTripper
+}
+
+// RoundTrip sends req through the stack for its host
+func (t *HostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.stack(req.URL.Host).RoundTrip(req)
+}
+
+// stack returns the transports for host, building them on first use
+func (t *HostTransport) stack(host string) http.RoundTripper {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	cfg, ok := t.Hosts[host]
+	if !ok {
+		return transport
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rt, ok := t.stacks[host]; ok {
+		return rt
+	}
+
+	// Built from the inside out, so headers are added once per request and
+	// each retry waits for the rate limit
+	rt := transport
+	if cfg.RateLimit.limited() {
+		rt = &RateLimitTransport{Transport: rt, Global: cfg.RateLimit}
+	}
+	if cfg.Retry != nil {
+		cfg.Retry.Transport = rt
+		rt = cfg.Retry
+	}
+	if cfg.Timeout > 0 || cfg.HeaderTimeout > 0 {
+		rt = &TimeoutTransport{Transport: rt, Timeout: cfg.Timeout, HeaderTimeout: cfg.HeaderTimeout}
+	}
+	if len(cfg.Headers) > 0 {
+		rt = &HeaderTransport{Transport: rt, Headers: cfg.Headers}
+	}
+
+	if t.stacks == nil {
+		t.stacks = make(map[string]http.RoundTripper)
+	}
+	t.stacks[host] = rt
+	return rt
+}
+
+// WithHostConfig applies cfg to the client's requests to host (as in
+// URL.Host), including its Redaction in the debug output
+func WithHostConfig(host string, cfg HostConfig) Option {
+	return func(c *clientConfig) {
+		if c.hosts == nil {
+			c.hosts = &HostTransport{Hosts: make(map[string]HostConfig)}
+		}
+		c.hosts.Hosts[host] = cfg
+
+		if cfg.Redaction != nil {
+			if c.debug.HostRedaction == nil {
+				c.debug.HostRedaction = make(map[string]Redaction)
+			}
+			c.debug.HostRedaction[host] = *cfg.Redaction
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// httpdbg/jsonl.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Format selects how DebugTransport writes to its Output when no Logger
+// is configured
+type Format int
+
+const (
+	// FormatText writes the human-readable request and response blocks
+	FormatText Format = iota
+	// FormatJSON writes one JSON object per exchange (JSON Lines), ready
+	// for ingestion by ELK, Loki and similar
+	FormatJSON
+)
+
+// jsonLogger writes each entry as a single JSON line
+type jsonLogger struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONLogger returns a Logger writing one JSON object per line to w.
+// Keys keep their field order after timestamp, level and msg; durations
+// are written as floating-point milliseconds with an "_ms" key suffix.
+func NewJSONLogger(w io.Writer) Logger {
+	return jsonLogger{mu: new(sync.Mutex), w: w}
+}
+
+func (j jsonLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	var buf bytes.Buffer
+	encodeJSONEntry(&buf, level, msg, fields)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.w.Write(buf.Bytes())
+}
+
+// encodeJSONEntry appends an entry to buf as a JSON line
+func encodeJSONEntry(buf *bytes.Buffer, level Level, msg string, fields []Field) {
+	buf.WriteByte('{')
+	writeJSONField(buf, "timestamp", time.Now().UTC().Format(time.RFC3339Nano))
+	buf.WriteByte(',')
+	writeJSONField(buf, "level", level.String())
+	buf.WriteByte(',')
+	writeJSONField(buf, "msg", msg)
+	for _, f := range fields {
+		buf.WriteByte(',')
+		if d, ok := f.Value.(time.Duration); ok {
+			writeJSONField(buf, f.Key+"_ms", float64(d)/float64(time.Millisecond))
+			continue
+		}
+		writeJSONField(buf, f.Key, f.Value)
+	}
+	buf.WriteString("}\n")
+}
+
+// writeJSONField appends "key":value, falling back to the value's string
+// form when it can't be encoded
+func writeJSONField(buf *bytes.Buffer, key string, value any) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+}
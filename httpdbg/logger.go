@@ -0,0 +1,186 @@
+// httpdbg/logger.go
+package httpdbg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Level is the severity of a structured log entry
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the lower-case level name
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// Field is a key/value pair attached to a log entry. Header fields hold
+// a map[string]string.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Logger receives one structured entry per exchange. Implement it to
+// route HTTP dumps into an existing logging stack; adapters for slog
+// (NewSlogLogger), zap (httpdbg/zapdbg) and zerolog (httpdbg/zerologdbg)
+// are provided, and the Sink implementations HTTPSink and SyslogSink ship
+// entries to remote collectors.
+type Logger interface {
+	Log(ctx context.Context, level Level, msg string, fields ...Field)
+}
+
+// logger returns the structured logger to use, or nil for text output.
+// w is the request's output, used by FormatJSON.
+func (d *DebugTransport) logger(w io.Writer) Logger {
+	if d.Logger != nil {
+		return d.Logger
+	}
+	if d.Slog != nil {
+		return NewSlogLogger(d.Slog)
+	}
+	if d.Format == FormatJSON {
+		return jsonLogger{mu: &d.mu, w: w}
+	}
+	return nil
+}
+
+// logStructured emits a single structured entry describing the exchange.
+// respSize is the full response size, which a streamed respBody may not hold.
+func (d *DebugTransport) logStructured(x *exchange, resp *http.Response, respBody []byte, respSize int64, duration time.Duration, err error) {
+	l, req, reqBody := x.logger, x.req, x.requestBody()
+
+	// A partial body can't be decoded or redacted, so log it only when it
+	// was captured in full
+	reqSize := x.requestSize()
+	if int64(len(reqBody)) < reqSize {
+		reqBody = nil
+	}
+
+	fields := []Field{
+		{"method", req.Method},
+		{"url", x.redaction.redactURL(req.URL)},
+		{"duration", duration},
+		{"request_size", reqSize},
+		{"request_headers", headerMap(x.redaction.redactHeaders(req.Header))},
+	}
+	if x.reqSkipped {
+		fields = append(fields, Field{"request_body", captureSkipped})
+	} else if len(x.graphQL) > 0 {
+		fields = append(fields, graphQLFields(x.graphQL)...)
+	} else if len(reqBody) > 0 {
+		fields = append(fields, Field{"request_body", d.bodyLog(x, nil, req.Header, reqBody, false, false)})
+	}
+	if d.Curl {
+		fields = append(fields, Field{"curl", d.curlCommand(req, reqBody)})
+	}
+	fields = append(fields, Field{"exchange", x.seq})
+	if x.id != "" {
+		fields = append(fields, Field{"request_id", x.id})
+	}
+	if attempt := attemptFrom(req); attempt > 0 {
+		fields = append(fields, Field{"attempt", attempt})
+	}
+	if x.repeated > 0 {
+		fields = append(fields, Field{"repeated", x.repeated})
+	}
+	if d.SOAP {
+		fields = append(fields, soapFields(soapAction(req.Header, reqBody), "")...)
+	}
+	fields = append(fields, d.redirectFields(req)...)
+	fields = append(fields, x.proxy.fields()...)
+	fields = append(fields, x.conn.fields()...)
+	insecure := d.insecure(req)
+	if insecure {
+		fields = append(fields, Field{"tls_insecure", true})
+	}
+	if x.timings != nil {
+		fields = append(fields, x.timings.fields()...)
+	}
+
+	if err != nil {
+		fields = append(fields, Field{"error", err.Error()})
+		if phase := x.phase.phase(); phase != "" {
+			fields = append(fields, Field{"phase", phase})
+		}
+		if kind := errorKind(err); kind != "" {
+			fields = append(fields, Field{"error_kind", kind})
+		}
+		l.Log(req.Context(), LevelError, "http request failed", fields...)
+		return
+	}
+
+	fields = append(fields,
+		Field{"status", resp.StatusCode},
+		Field{"response_size", respSize},
+		Field{"response_headers", headerMap(x.redaction.redactHeaders(resp.Header))},
+	)
+	fields = append(fields, protocolFields(resp)...)
+	if x.respSkipped {
+		fields = append(fields, Field{"response_body", captureSkipped})
+	} else if len(respBody) > 0 {
+		fields = append(fields, Field{"response_body", d.bodyLog(x, resp, resp.Header, respBody, false, false)})
+	}
+
+	level := LevelDebug
+	if insecure {
+		level = LevelWarn
+	}
+	if d.TLSInfo && resp.TLS != nil {
+		fields = append(fields, d.tlsFields(resp.TLS)...)
+		if d.certExpiring(resp.TLS) {
+			fields = append(fields, Field{"cert_expiring", true})
+			level = LevelWarn
+		}
+	}
+	if d.isSlow(duration) {
+		fields = append(fields, Field{"slow", true})
+		level = LevelWarn
+	}
+	if d.SOAP {
+		if fault := soapFault(resp.Header, respBody); fault != "" {
+			fields = append(fields, soapFields("", fault)...)
+			level = LevelWarn
+		}
+	}
+	if len(x.graphQL) > 0 {
+		if errs := graphQLErrors(resp.Header, respBody); len(errs) > 0 {
+			fields = append(fields, Field{"graphql_errors", errs})
+			level = LevelWarn
+		}
+	}
+	if resp.StatusCode >= 500 {
+		level = LevelError
+	} else if resp.StatusCode >= 400 {
+		level = LevelWarn
+	}
+	l.Log(req.Context(), level, "http exchange", fields...)
+}
+
+// headerMap flattens headers into a single value per name
+func headerMap(h http.Header) map[string]string {
+	m := make(map[string]string, len(h))
+	for k, v := range h {
+		m[k] = strings.Join(v, ", ")
+	}
+	return m
+}
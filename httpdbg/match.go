@@ -0,0 +1,86 @@
+// httpdbg/match.go
+package httpdbg
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Volatile values replaced by Matcher.NormalizeBodies
+var (
+	uuidPattern      = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	timestampPattern = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`)
+)
+
+// Matcher decides whether a request matches a recorded one, for replaying
+// recorded traffic. The zero value compares method, URL, headers and body
+// exactly.
+type Matcher struct {
+	// MethodAndPathOnly ignores the query, headers and body
+	MethodAndPathOnly bool
+	// IgnoreHeaders lists headers left out of the comparison
+	// (case-insensitive); IgnoreAllHeaders skips headers entirely
+	IgnoreHeaders    []string
+	IgnoreAllHeaders bool
+	// NormalizeBodies replaces UUIDs and ISO 8601 timestamps in both
+	// bodies before comparing them
+	NormalizeBodies bool
+	// BodyPatterns are extra volatile patterns removed from bodies
+	BodyPatterns []*regexp.Regexp
+}
+
+// Match reports whether req with body matches the recorded request
+func (m *Matcher) Match(req *http.Request, body []byte, recorded *http.Request, recordedBody []byte) bool {
+	if req.Method != recorded.Method || req.URL.Path != recorded.URL.Path {
+		return false
+	}
+	if m.MethodAndPathOnly {
+		return true
+	}
+
+	if req.URL.Host != recorded.URL.Host || req.URL.Query().Encode() != recorded.URL.Query().Encode() {
+		return false
+	}
+	if !m.IgnoreAllHeaders && !m.headersMatch(req.Header, recorded.Header) {
+		return false
+	}
+	return bytes.Equal(m.normalize(body), m.normalize(recordedBody))
+}
+
+// headersMatch compares headers, skipping IgnoreHeaders
+func (m *Matcher) headersMatch(a, b http.Header) bool {
+	ignored := func(name string) bool {
+		for _, h := range m.IgnoreHeaders {
+			if strings.EqualFold(h, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, pair := range [][2]http.Header{{a, b}, {b, a}} {
+		for k, v := range pair[0] {
+			if ignored(k) {
+				continue
+			}
+			if strings.Join(v, "\x00") != strings.Join(pair[1][k], "\x00") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// normalize blanks out the volatile parts of a body
+func (m *Matcher) normalize(body []byte) []byte {
+	if m.NormalizeBodies {
+		body = uuidPattern.ReplaceAll(body, []byte("<uuid>"))
+		body = timestampPattern.ReplaceAll(body, []byte("<timestamp>"))
+	}
+	for _, p := range m.BodyPatterns {
+		body = p.ReplaceAll(body, nil)
+	}
+	return body
+}
@@ -0,0 +1,89 @@
+// httpdbg/multipart.go
+package httpdbg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// maxInlinePart is the largest text part shown in a multipart summary;
+// bigger parts and files are only described
+const maxInlinePart = 256
+
+// multipartBoundary returns the boundary of a multipart content type, or
+// "" if contentType isn't multipart
+func multipartBoundary(contentType string) string {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return ""
+	}
+	return params["boundary"]
+}
+
+// multipartSummary describes each part of a multipart body: its name,
+// filename, content type and size, with small text parts inline. Parts
+// named like a redacted JSON field are masked, and JSON parts have their
+// fields masked.
+func multipartSummary(r *Redaction, contentType, boundary string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	var lines []string
+	mr := multipart.NewReader(bytes.NewReader(body), boundary)
+	for n := 1; ; n++ {
+		part, err := mr.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("  [malformed: %v]", err))
+			break
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("  [%d] malformed: %v", n, err))
+			break
+		}
+		lines = append(lines, "  "+partSummary(r, n, part, data))
+	}
+
+	header := fmt.Sprintf("[%s body: %d parts, %d bytes]", mediaType, len(lines), len(body))
+	return strings.Join(append([]string{header}, lines...), "\n")
+}
+
+// partSummary describes the nth part of a multipart body
+func partSummary(r *Redaction, n int, part *multipart.Part, data []byte) string {
+	var desc []string
+	if name := part.FormName(); name != "" {
+		desc = append(desc, fmt.Sprintf("name=%q", name))
+	}
+	if filename := part.FileName(); filename != "" {
+		desc = append(desc, fmt.Sprintf("filename=%q", filename))
+	}
+
+	partType := part.Header.Get("Content-Type")
+	meta := fmt.Sprintf("%d bytes", len(data))
+	if partType != "" {
+		meta = partType + ", " + meta
+	}
+	line := fmt.Sprintf("[%d] %s (%s)", n, strings.Join(desc, " "), meta)
+
+	// Files and large or binary parts are only described
+	if part.FileName() != "" || len(data) > maxInlinePart || isBinary(partType, data) {
+		return line
+	}
+
+	var fields []string
+	if !r.DisableDefaults {
+		fields = append(fields, DefaultRedactedJSONFields...)
+	}
+	fields = append(fields, r.JSONFields...)
+	if name := part.FormName(); name != "" && jsonFieldRedacted(name, name, fields) {
+		return line + ": " + r.mask(string(data))
+	}
+	return line + ": " + string(r.redactBody(data))
+}
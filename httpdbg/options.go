@@ -0,0 +1,156 @@
+// httpdbg/options.go
+package httpdbg
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// clientConfig is what NewClient options act on
+type clientConfig struct {
+	client *http.Client
+	debug  *DebugTransport
+	// custom is a base RoundTripper given to WithTransport that isn't an
+	// *http.Transport; it can't take transport-level options
+	custom http.RoundTripper
+	// base is the underlying transport, created by the first option that
+	// needs one
+	base *http.Transport
+	// bandwidth simulates a slow network under everything else
+	bandwidth *NetworkProfile
+	// hosts applies WithHostConfig settings on top of base
+	hosts *HostTransport
+	// retry and headers wrap the DebugTransport, so each attempt and the
+	// added headers are logged
+	retry   *RetryTransport
+	headers http.Header
+}
+
+// transport returns the underlying transport for options to configure
+func (c *clientConfig) transport() *http.Transport {
+	if c.base == nil {
+		c.base = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	return c.base
+}
+
+// build assembles the client's transports, from the outside in: headers,
+// retries, debug logging, per-host settings, the bandwidth simulation,
+// then the base transport
+func (c *clientConfig) build() *http.Client {
+	var rt http.RoundTripper
+	switch {
+	case c.custom != nil:
+		rt = c.custom
+	case c.base != nil:
+		rt = c.base
+	}
+	if c.bandwidth != nil {
+		rt = &BandwidthTransport{Transport: rt, Profile: *c.bandwidth}
+	}
+	if c.hosts != nil {
+		c.hosts.Transport = rt
+		rt = c.hosts
+	}
+
+	c.debug.Transport = rt
+	rt = c.debug
+
+	if c.retry != nil {
+		c.retry.Transport = rt
+		rt = c.retry
+	}
+	if len(c.headers) > 0 {
+		rt = &HeaderTransport{Transport: rt, Headers: c.headers}
+	}
+
+	c.client.Transport = rt
+	return c.client
+}
+
+// Option configures a client built by NewClient
+type Option func(*clientConfig)
+
+// WithTransport sends requests through base instead of a copy of
+// http.DefaultTransport. An *http.Transport is copied, and the TLS and
+// proxy options apply to the copy; they are ignored for any other
+// RoundTripper. Give it before those options, which it would otherwise
+// undo.
+func WithTransport(base http.RoundTripper) Option {
+	return func(c *clientConfig) {
+		if t, ok := base.(*http.Transport); ok {
+			c.base, c.custom = t.Clone(), nil
+			return
+		}
+		c.base, c.custom = nil, base
+	}
+}
+
+// WithTimeout limits the time for each request, including redirects,
+// retries and reading the response body
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *clientConfig) {
+		c.client.Timeout = timeout
+	}
+}
+
+// WithRedaction sets the debug output's Redaction
+func WithRedaction(r Redaction) Option {
+	return func(c *clientConfig) {
+		c.debug.Redaction = r
+	}
+}
+
+// WithRedactionProfile adds redaction rules for the hosts matching the
+// glob host, on top of those of WithRedaction
+func WithRedactionProfile(host string, r Redaction) Option {
+	return func(c *clientConfig) {
+		c.debug.RedactionProfiles = append(c.debug.RedactionProfiles, RedactionProfile{Host: host, Redaction: r})
+	}
+}
+
+// WithWriter sends the debug output to w instead of os.Stdout
+func WithWriter(w io.Writer) Option {
+	return func(c *clientConfig) {
+		c.debug.Output = w
+	}
+}
+
+// WithDebugTransport lets fn set any other DebugTransport field, such as
+// Format, Pretty or Trace
+func WithDebugTransport(fn func(*DebugTransport)) Option {
+	return func(c *clientConfig) {
+		fn(c.debug)
+	}
+}
+
+// WithRetry retries failed requests up to maxAttempts times in all, with
+// the RetryTransport defaults otherwise. Each attempt is logged.
+func WithRetry(maxAttempts int) Option {
+	return func(c *clientConfig) {
+		c.retry = &RetryTransport{MaxAttempts: maxAttempts}
+	}
+}
+
+// WithHeaders adds headers to requests that don't already set them. They
+// are added before logging, so they show (redacted) in the debug output.
+func WithHeaders(headers http.Header) Option {
+	return func(c *clientConfig) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		for k, v := range headers {
+			c.headers[k] = append([]string(nil), v...)
+		}
+	}
+}
+
+// WithCookieJar stores and sends cookies with jar. Cookie and Set-Cookie
+// headers are logged with their values masked; use a PersistentJar to keep
+// a session across runs.
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *clientConfig) {
+		c.client.Jar = jar
+	}
+}
@@ -0,0 +1,76 @@
+// httpdbg/oteldbg/oteldbg.go
+package oteldbg
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans' tracer
+const instrumentationName = "your/path/to/httpdbg/oteldbg"
+
+// Transport is a RoundTripper that starts a client span per request and
+// injects a W3C traceparent header. Wrap a DebugTransport with it so the
+// logged requests show the injected header:
+//
+//	client := &http.Client{Transport: oteldbg.New(nil, &httpdbg.DebugTransport{})}
+type Transport struct {
+	// Transport is the RoundTripper being traced; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// New creates a Transport wrapping next. A nil tp uses the global
+// TracerProvider.
+func New(tp trace.TracerProvider, next http.RoundTripper) *Transport {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return &Transport{
+		Transport:  next,
+		tracer:     tp.Tracer(instrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// RoundTrip performs the request inside a client span
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+	defer span.End()
+
+	// Inject into a copy; a RoundTripper must not modify the caller's request
+	req = req.Clone(ctx)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
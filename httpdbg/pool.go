@@ -0,0 +1,92 @@
+// httpdbg/pool.go
+package httpdbg
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// maxPooledBuffer is the largest buffer kept for reuse; bigger ones are
+// left to the garbage collector so one large download doesn't pin memory
+const maxPooledBuffer = 1 << 20
+
+// bufferPool holds the buffers bodies are captured into
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// pooledBuffer is a captured body shared by the replacement body handed
+// on to the transport or caller and by the logging code. The buffer goes
+// back to the pool once every holder has released it.
+type pooledBuffer struct {
+	buf  *bytes.Buffer
+	refs int32
+	// free, if set, runs after the last release
+	free func()
+}
+
+// readPooled reads r into a pooled buffer held by refs holders. Read
+// errors are ignored; whatever was read is kept.
+func readPooled(r io.Reader, refs int32) *pooledBuffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.ReadFrom(r)
+	return &pooledBuffer{buf: buf, refs: refs}
+}
+
+// Bytes returns the captured body; nil for a nil buffer
+func (p *pooledBuffer) Bytes() []byte {
+	if p == nil {
+		return nil
+	}
+	return p.buf.Bytes()
+}
+
+// release drops one reference, returning the buffer to the pool after the last
+func (p *pooledBuffer) release() {
+	if p == nil || atomic.AddInt32(&p.refs, -1) != 0 {
+		return
+	}
+	if p.buf.Cap() <= maxPooledBuffer {
+		bufferPool.Put(p.buf)
+	}
+	p.buf = nil
+	if p.free != nil {
+		p.free()
+	}
+}
+
+// body returns a reader over the buffer that releases its reference on Close
+func (p *pooledBuffer) body() io.ReadCloser {
+	return &pooledBody{Reader: bytes.NewReader(p.buf.Bytes()), p: p}
+}
+
+// getBody returns another body over the buffer, for GetBody, holding a
+// reference of its own until closed. It fails once the buffer is released.
+func (p *pooledBuffer) getBody() (io.ReadCloser, error) {
+	for {
+		refs := atomic.LoadInt32(&p.refs)
+		if refs <= 0 {
+			return nil, errors.New("httpdbg: request body already released")
+		}
+		if atomic.CompareAndSwapInt32(&p.refs, refs, refs+1) {
+			return p.body(), nil
+		}
+	}
+}
+
+// pooledBody is a request or response body backed by a pooled buffer
+type pooledBody struct {
+	*bytes.Reader
+	p    *pooledBuffer
+	once sync.Once
+}
+
+// Close releases the body's reference to the buffer
+func (b *pooledBody) Close() error {
+	b.once.Do(b.p.release)
+	return nil
+}
@@ -0,0 +1,71 @@
+// httpdbg/pool_test.go
+package httpdbg
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// benchBody is a response body of a typical JSON API size
+var benchBody = []byte(`{"items":[` + strings.Repeat(`{"id":12345,"name":"example item","tags":["a","b","c"]},`, 200) + `{}]}`)
+
+// stubTransport answers every request with benchBody
+type stubTransport struct{}
+
+func (stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(benchBody)),
+		ContentLength: int64(len(benchBody)),
+		Request:       req,
+	}, nil
+}
+
+// BenchmarkDebugTransportCapturePooled captures bodies the way
+// DebugTransport does, into pooled buffers released on Close
+func BenchmarkDebugTransportCapturePooled(b *testing.B) {
+	d := &DebugTransport{}
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchBody)))
+	for i := 0; i < b.N; i++ {
+		p, body := d.captureBody(io.NopCloser(bytes.NewReader(benchBody)), 2)
+		io.Copy(io.Discard, body)
+		body.Close()
+		p.release()
+	}
+}
+
+// BenchmarkDebugTransportCaptureUnpooled captures bodies into a fresh
+// buffer each time, as before pooling
+func BenchmarkDebugTransportCaptureUnpooled(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchBody)))
+	for i := 0; i < b.N; i++ {
+		buf, _ := io.ReadAll(bytes.NewReader(benchBody))
+		body := io.NopCloser(bytes.NewReader(buf))
+		io.Copy(io.Discard, body)
+		body.Close()
+	}
+}
+
+// BenchmarkDebugTransportRoundTrip measures a logged exchange end to end
+func BenchmarkDebugTransportRoundTrip(b *testing.B) {
+	client := &http.Client{Transport: &DebugTransport{Transport: stubTransport{}, Output: io.Discard}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		resp, err := client.Get("http://example.com/items")
+		if err != nil {
+			b.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,262 @@
+// httpdbg/pretty.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"mime"
+	"os"
+	"strings"
+)
+
+// ANSI escape sequences used by colorBody
+const (
+	ansiReset  = "\x1b[0m"
+	ansiKey    = "\x1b[36m" // cyan
+	ansiString = "\x1b[32m" // green
+	ansiNumber = "\x1b[33m" // yellow
+	ansiLit    = "\x1b[35m" // magenta
+	ansiTag    = "\x1b[34m" // blue
+)
+
+// Body kinds recognized by the pretty printer
+const (
+	kindOther = iota
+	kindJSON
+	kindXML
+)
+
+// bodyKind classifies a body by content type, falling back to its first byte
+func bodyKind(contentType string, body []byte) int {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case strings.HasSuffix(mediaType, "json"):
+			return kindJSON
+		case strings.HasSuffix(mediaType, "xml"):
+			return kindXML
+		}
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return kindOther
+	}
+	switch trimmed[0] {
+	case '{', '[':
+		return kindJSON
+	case '<':
+		return kindXML
+	}
+	return kindOther
+}
+
+// prettyBody indents a JSON or XML body; anything that fails to parse is
+// returned unchanged
+func prettyBody(kind int, body []byte) []byte {
+	switch kind {
+	case kindJSON:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, bytes.TrimSpace(body), "", "  "); err == nil {
+			return buf.Bytes()
+		}
+	case kindXML:
+		if out, err := indentXML(body); err == nil {
+			return out
+		}
+	}
+	return body
+}
+
+// indentXML re-indents an XML document, dropping whitespace-only text
+// between elements. Tokens are written back as read, so namespace
+// prefixes such as soap:Envelope survive; elements with no content are
+// written self-closing.
+func indentXML(body []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+
+	var buf bytes.Buffer
+	var depth int
+	// open is set while a start tag awaits its content, and text is set
+	// once an element has text, so its end tag stays on the same line
+	var open, text bool
+	newline := func() {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(strings.Repeat("  ", depth))
+	}
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if open {
+			if _, ok := tok.(xml.EndElement); ok {
+				buf.WriteString("/>")
+				open = false
+				depth--
+				continue
+			}
+			buf.WriteByte('>')
+			open = false
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			newline()
+			buf.WriteString("<" + xmlName(t.Name))
+			for _, a := range t.Attr {
+				buf.WriteString(" " + xmlName(a.Name) + `="`)
+				xml.EscapeText(&buf, []byte(a.Value))
+				buf.WriteByte('"')
+			}
+			open, text = true, false
+			depth++
+		case xml.EndElement:
+			depth--
+			if !text {
+				newline()
+			}
+			buf.WriteString("</" + xmlName(t.Name) + ">")
+			text = false
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			xml.EscapeText(&buf, bytes.TrimSpace(t))
+			text = true
+		case xml.Comment:
+			newline()
+			buf.WriteString("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			newline()
+			buf.WriteString("<?" + t.Target + " " + string(t.Inst) + "?>")
+		case xml.Directive:
+			newline()
+			buf.WriteString("<!" + string(t) + ">")
+		}
+	}
+	if open {
+		buf.WriteString(">")
+	}
+	return buf.Bytes(), nil
+}
+
+// xmlName writes a raw token name back with its namespace prefix
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// colorBody highlights JSON or XML with ANSI colors. It scans tokens
+// rather than parsing, so truncated bodies are colored too.
+func colorBody(kind int, body []byte) []byte {
+	switch kind {
+	case kindJSON:
+		return colorJSON(body)
+	case kindXML:
+		return colorXML(body)
+	}
+	return body
+}
+
+// colorJSON colors keys, strings, numbers and literals
+func colorJSON(body []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(body); {
+		c := body[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(body) && body[end] != '"' {
+				if body[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(body) {
+				end++
+			}
+			if end > len(body) {
+				end = len(body)
+			}
+
+			// A string followed by a colon is an object key
+			color := ansiString
+			rest := bytes.TrimLeft(body[end:], " \t\r\n")
+			if len(rest) > 0 && rest[0] == ':' {
+				color = ansiKey
+			}
+			buf.WriteString(color)
+			buf.Write(body[i:end])
+			buf.WriteString(ansiReset)
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(body) && strings.IndexByte("0123456789.eE+-", body[end]) >= 0 {
+				end++
+			}
+			buf.WriteString(ansiNumber)
+			buf.Write(body[i:end])
+			buf.WriteString(ansiReset)
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i + 1
+			for end < len(body) && body[end] >= 'a' && body[end] <= 'z' {
+				end++
+			}
+			buf.WriteString(ansiLit)
+			buf.Write(body[i:end])
+			buf.WriteString(ansiReset)
+			i = end
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.Bytes()
+}
+
+// colorXML colors tags, leaving text content plain
+func colorXML(body []byte) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(body); {
+		if body[i] != '<' {
+			buf.WriteByte(body[i])
+			i++
+			continue
+		}
+		end := bytes.IndexByte(body[i:], '>')
+		if end < 0 {
+			end = len(body)
+		} else {
+			end += i + 1
+		}
+		buf.WriteString(ansiTag)
+		buf.Write(body[i:end])
+		buf.WriteString(ansiReset)
+		i = end
+	}
+	return buf.Bytes()
+}
+
+// isTerminal reports whether w is a terminal that should get colors
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,89 @@
+// httpdbg/promdbg/promdbg.go
+package promdbg
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Transport is a RoundTripper that records Prometheus metrics for every
+// request. It composes with httpdbg.DebugTransport in either order:
+//
+//	metrics, err := promdbg.New(registry, &httpdbg.DebugTransport{})
+//	client := &http.Client{Transport: metrics}
+type Transport struct {
+	// Transport is the RoundTripper being measured; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates a Transport wrapping next and registers its metrics on reg:
+//
+//	httpdbg_client_requests_total{method,host,status}
+//	httpdbg_client_errors_total{method,host}
+//	httpdbg_client_request_duration_seconds{method,host,status}
+//
+// status is the response class ("2xx", "4xx", ...), or "error" when the
+// transport failed.
+func New(reg prometheus.Registerer, next http.RoundTripper) (*Transport, error) {
+	t := &Transport{
+		Transport: next,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "httpdbg_client_requests_total",
+			Help: "HTTP client requests by method, host and status class.",
+		}, []string{"method", "host", "status"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "httpdbg_client_errors_total",
+			Help: "HTTP client requests that failed without a response.",
+		}, []string{"method", "host"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "httpdbg_client_request_duration_seconds",
+			Help:    "Time until HTTP client response headers arrived.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "host", "status"}),
+	}
+
+	for _, c := range []prometheus.Collector{t.requests, t.errors, t.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// RoundTrip performs the request and records its metrics
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(req)
+	elapsed := time.Since(start).Seconds()
+
+	status := "error"
+	if err != nil {
+		t.errors.WithLabelValues(req.Method, req.URL.Host).Inc()
+	} else {
+		status = statusClass(resp.StatusCode)
+	}
+	t.requests.WithLabelValues(req.Method, req.URL.Host, status).Inc()
+	t.duration.WithLabelValues(req.Method, req.URL.Host, status).Observe(elapsed)
+
+	return resp, err
+}
+
+// statusClass buckets a status code into "1xx" through "5xx"
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return strconv.Itoa(code)
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,98 @@
+// httpdbg/protocol.go
+package httpdbg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// protocolText describes the protocol a response came over, e.g.
+// "HTTP/2.0 (ALPN h2)", or "HTTP/1.1 (no ALPN)" when a TLS server didn't
+// take part in the negotiation
+func protocolText(resp *http.Response) string {
+	if resp.TLS == nil {
+		return resp.Proto
+	}
+	if alpn := resp.TLS.NegotiatedProtocol; alpn != "" {
+		return fmt.Sprintf("%s (ALPN %s)", resp.Proto, alpn)
+	}
+	return resp.Proto + " (no ALPN)"
+}
+
+// protocolFields returns the protocol as structured log fields
+func protocolFields(resp *http.Response) []Field {
+	fields := []Field{{"proto", resp.Proto}}
+	if resp.TLS != nil {
+		fields = append(fields, Field{"alpn", resp.TLS.NegotiatedProtocol})
+	}
+	return fields
+}
+
+// HTTP1Transport returns a transport that only speaks HTTP/1.1, never
+// offering HTTP/2 to servers, for telling protocol bugs apart
+func HTTP1Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	setHTTP1(t)
+	return t
+}
+
+// HTTP2Transport returns a transport that requires HTTP/2: the TLS
+// handshake fails with servers that don't agree to it over ALPN rather
+// than falling back to HTTP/1.1. Cleartext (h2c) isn't supported, so
+// plain http:// requests still go over HTTP/1.1.
+func HTTP2Transport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	setHTTP2(t)
+	return t
+}
+
+// setHTTP1 limits t to HTTP/1.1
+func setHTTP1(t *http.Transport) {
+	t.ForceAttemptHTTP2 = false
+	// A non-nil, empty map turns HTTP/2 off
+	t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	cfg := tlsClientConfig(t)
+	cfg.NextProtos = []string{"http/1.1"}
+}
+
+// setHTTP2 makes t require HTTP/2 over TLS
+func setHTTP2(t *http.Transport) {
+	t.ForceAttemptHTTP2 = true
+	t.TLSNextProto = nil
+	cfg := tlsClientConfig(t)
+	cfg.NextProtos = []string{"h2"}
+	verify := cfg.VerifyConnection
+	cfg.VerifyConnection = func(state tls.ConnectionState) error {
+		if state.NegotiatedProtocol != "h2" {
+			return fmt.Errorf("server did not negotiate HTTP/2 (ALPN %q)", state.NegotiatedProtocol)
+		}
+		if verify != nil {
+			return verify(state)
+		}
+		return nil
+	}
+}
+
+// tlsClientConfig returns t's TLS configuration, creating it if need be
+func tlsClientConfig(t *http.Transport) *tls.Config {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
+
+// WithHTTP1 makes the client speak only HTTP/1.1, as HTTP1Transport does.
+// Give at most one of WithHTTP1 and WithHTTP2.
+func WithHTTP1() Option {
+	return func(c *clientConfig) {
+		setHTTP1(c.transport())
+	}
+}
+
+// WithHTTP2 makes the client require HTTP/2, as HTTP2Transport does
+func WithHTTP2() Option {
+	return func(c *clientConfig) {
+		setHTTP2(c.transport())
+	}
+}
@@ -0,0 +1,166 @@
+// httpdbg/protodbg/protodbg.go
+package protodbg
+
+import (
+	"mime"
+	"net/http"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"your/path/to/httpdbg"
+)
+
+// protobufTypes are the media types decoded as protobuf
+var protobufTypes = map[string]bool{
+	"application/protobuf":            true,
+	"application/x-protobuf":          true,
+	"application/vnd.google.protobuf": true,
+}
+
+// typeParams are the Content-Type parameters that may name the message
+// type, e.g. "application/x-protobuf; messageType=acme.v1.User"
+var typeParams = []string{"messagetype", "proto", "type"}
+
+// Decoder is an httpdbg.BodyDecoder that logs protobuf bodies as JSON (or
+// text format) instead of a binary summary:
+//
+//	dec := &protodbg.Decoder{}
+//	dec.Register(httpdbg.Rule{Path: regexp.MustCompile(`^/v1/users`)}, &pb.GetUserRequest{}, &pb.User{})
+//	transport := &httpdbg.DebugTransport{BodyDecoder: dec}
+//
+// A message type named in the Content-Type is looked up in Resolver;
+// otherwise the first registered route matching the request decides.
+// JSON uses the field names of the .proto file, so Redaction.JSONFields
+// such as "access_token" apply to it.
+type Decoder struct {
+	// Resolver finds the message types named in Content-Type; defaults to
+	// protoregistry.GlobalTypes
+	Resolver protoregistry.MessageTypeResolver
+	// Text logs the protobuf text format instead of JSON
+	Text bool
+
+	mu     sync.RWMutex
+	routes []route
+}
+
+// route maps the requests matched by a Rule to their message types
+type route struct {
+	match    httpdbg.Rule
+	request  protoreflect.MessageType
+	response protoreflect.MessageType
+}
+
+// Register decodes the bodies of requests matching match as request, and
+// their responses as response. Either may be nil.
+func (d *Decoder) Register(match httpdbg.Rule, request, response proto.Message) {
+	var reqType, respType protoreflect.MessageType
+	if request != nil {
+		reqType = request.ProtoReflect().Type()
+	}
+	if response != nil {
+		respType = response.ProtoReflect().Type()
+	}
+	d.register(route{match, reqType, respType})
+}
+
+// RegisterDescriptors is Register for message descriptors, such as those
+// loaded from a FileDescriptorSet, when the generated types aren't
+// linked in. Either may be nil.
+func (d *Decoder) RegisterDescriptors(match httpdbg.Rule, request, response protoreflect.MessageDescriptor) {
+	var reqType, respType protoreflect.MessageType
+	if request != nil {
+		reqType = dynamicpb.NewMessageType(request)
+	}
+	if response != nil {
+		respType = dynamicpb.NewMessageType(response)
+	}
+	d.register(route{match, reqType, respType})
+}
+
+// register adds a route
+func (d *Decoder) register(r route) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes = append(d.routes, r)
+}
+
+// DecodeBody implements httpdbg.BodyDecoder
+func (d *Decoder) DecodeBody(req *http.Request, resp *http.Response, body []byte) ([]byte, bool) {
+	h := req.Header
+	if resp != nil {
+		h = resp.Header
+	}
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !protobufTypes[mediaType] {
+		return nil, false
+	}
+
+	mt := d.messageType(req, resp != nil, params)
+	if mt == nil {
+		return nil, false
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(body, m); err != nil {
+		return nil, false
+	}
+
+	var out []byte
+	if d.Text {
+		out, err = prototext.MarshalOptions{Multiline: true, Resolver: d.anyResolver()}.Marshal(m)
+	} else {
+		out, err = protojson.MarshalOptions{UseProtoNames: true, Resolver: d.anyResolver()}.Marshal(m)
+	}
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// messageType finds the type of a body: named in its Content-Type, or
+// registered for the request
+func (d *Decoder) messageType(req *http.Request, response bool, params map[string]string) protoreflect.MessageType {
+	for _, p := range typeParams {
+		if name := params[p]; name != "" {
+			resolver := d.Resolver
+			if resolver == nil {
+				resolver = protoregistry.GlobalTypes
+			}
+			if mt, err := resolver.FindMessageByName(protoreflect.FullName(name)); err == nil {
+				return mt
+			}
+		}
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, r := range d.routes {
+		if !r.match.Matches(req) {
+			continue
+		}
+		mt := r.request
+		if response {
+			mt = r.response
+		}
+		if mt != nil {
+			return mt
+		}
+	}
+	return nil
+}
+
+// anyResolver resolves the contents of google.protobuf.Any fields
+func (d *Decoder) anyResolver() interface {
+	protoregistry.ExtensionTypeResolver
+	protoregistry.MessageTypeResolver
+} {
+	if r, ok := d.Resolver.(*protoregistry.Types); ok {
+		return r
+	}
+	return protoregistry.GlobalTypes
+}
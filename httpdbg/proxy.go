@@ -0,0 +1,140 @@
+// httpdbg/proxy.go
+package httpdbg
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// proxyKey is the context key for the proxy details of a request
+type proxyKey struct{}
+
+// proxyUse records which proxy a request went through and, for a new
+// tunnelled connection, how the proxy answered the CONNECT
+type proxyUse struct {
+	mu sync.Mutex
+	// url is nil when the request went direct
+	url     *url.URL
+	connect *http.Response
+}
+
+// withProxyUse returns a context that records the proxy used for a request
+func withProxyUse(ctx context.Context) (context.Context, *proxyUse) {
+	p := &proxyUse{}
+	return context.WithValue(ctx, proxyKey{}, p), p
+}
+
+// proxyUseFrom returns the request's proxy record, if any
+func proxyUseFrom(ctx context.Context) *proxyUse {
+	p, _ := ctx.Value(proxyKey{}).(*proxyUse)
+	return p
+}
+
+// String describes the proxy and CONNECT result, e.g.
+// "http://proxy:3128 (CONNECT 200 Connection established)", or returns ""
+// for a direct request
+func (p *proxyUse) String() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.url == nil {
+		return ""
+	}
+	s := p.url.Redacted()
+	if p.connect != nil {
+		s += " (CONNECT " + p.connect.Status + ")"
+	}
+	return s
+}
+
+// refused reports whether the proxy answered the CONNECT with an error
+func (p *proxyUse) refused() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.connect != nil && p.connect.StatusCode/100 != 2
+}
+
+// fields returns the proxy details as structured log fields
+func (p *proxyUse) fields() []Field {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.url == nil {
+		return nil
+	}
+	fields := []Field{{"proxy", p.url.Redacted()}}
+	if p.connect != nil {
+		fields = append(fields, Field{"proxy_connect_status", p.connect.StatusCode})
+	}
+	return fields
+}
+
+// ProxyTransport returns a transport that sends requests through proxy,
+// which may be an http, https or socks5 URL. Hosts matching one of the
+// bypass globs, e.g. "localhost" or "*.internal", are reached directly. A
+// nil proxy uses the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables instead, ignoring bypass.
+//
+// When wrapped by DebugTransport, the proxy used and the proxy's answer to
+// CONNECT are logged with each response. CONNECT only happens when a new
+// tunnel is opened, so it is logged on the request that opened it.
+func ProxyTransport(proxy *url.URL, bypass ...string) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	setProxy(t, proxy, bypass)
+	return t
+}
+
+// setProxy points t at proxy and records its use for DebugTransport
+func setProxy(t *http.Transport, proxy *url.URL, bypass []string) {
+	choose := http.ProxyFromEnvironment
+	if proxy != nil {
+		choose = func(req *http.Request) (*url.URL, error) {
+			host := strings.ToLower(req.URL.Hostname())
+			for _, glob := range bypass {
+				if ok, _ := path.Match(strings.ToLower(glob), host); ok {
+					return nil, nil
+				}
+			}
+			return proxy, nil
+		}
+	}
+
+	t.Proxy = func(req *http.Request) (*url.URL, error) {
+		u, err := choose(req)
+		if p := proxyUseFrom(req.Context()); p != nil {
+			p.mu.Lock()
+			p.url = u
+			p.mu.Unlock()
+		}
+		return u, err
+	}
+	t.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+		if p := proxyUseFrom(ctx); p != nil {
+			p.mu.Lock()
+			p.connect = connectRes
+			p.mu.Unlock()
+		}
+		return nil
+	}
+}
+
+// WithProxy sends the client's requests through proxy, as set up by
+// ProxyTransport
+func WithProxy(proxy *url.URL, bypass ...string) Option {
+	return func(c *clientConfig) {
+		setProxy(c.transport(), proxy, bypass)
+	}
+}
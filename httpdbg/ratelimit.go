@@ -0,0 +1,81 @@
+// httpdbg/ratelimit.go
+package httpdbg
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit is the rate-limit state a server reported in its response
+// headers. Counts are -1 and times zero when the header was absent.
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	// Reset is when the quota refills (X-RateLimit-Reset)
+	Reset time.Time
+	// RetryAfter is the wait requested by a Retry-After header
+	RetryAfter time.Duration
+}
+
+// ParseRateLimit reads Retry-After and the X-RateLimit-* headers from
+// resp. ok is false when none of them is present.
+func ParseRateLimit(resp *http.Response) (rl RateLimit, ok bool) {
+	rl = RateLimit{Limit: -1, Remaining: -1}
+	now := time.Now()
+
+	if v := headerInt(resp.Header, "X-RateLimit-Limit", "RateLimit-Limit"); v >= 0 {
+		rl.Limit, ok = int(v), true
+	}
+	if v := headerInt(resp.Header, "X-RateLimit-Remaining", "RateLimit-Remaining"); v >= 0 {
+		rl.Remaining, ok = int(v), true
+	}
+
+	// Reset is either a Unix timestamp or a number of seconds from now
+	if v := headerInt(resp.Header, "X-RateLimit-Reset", "RateLimit-Reset"); v >= 0 {
+		if v > 1e9 {
+			rl.Reset = time.Unix(v, 0)
+		} else {
+			rl.Reset = now.Add(time.Duration(v) * time.Second)
+		}
+		ok = true
+	}
+
+	if v := strings.TrimSpace(resp.Header.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil && secs >= 0 {
+			rl.RetryAfter, ok = time.Duration(secs)*time.Second, true
+		} else if at, err := http.ParseTime(v); err == nil {
+			rl.RetryAfter, ok = at.Sub(now), true
+			if rl.RetryAfter < 0 {
+				rl.RetryAfter = 0
+			}
+		}
+	}
+	return rl, ok
+}
+
+// Wait returns how long the server asked clients to hold off: the
+// Retry-After delay, or the time until Reset once the quota is used up
+func (rl RateLimit) Wait() time.Duration {
+	if rl.RetryAfter > 0 {
+		return rl.RetryAfter
+	}
+	if rl.Remaining == 0 && !rl.Reset.IsZero() {
+		if wait := time.Until(rl.Reset); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// headerInt returns the first of the named headers holding a
+// non-negative integer, or -1
+func headerInt(h http.Header, names ...string) int64 {
+	for _, name := range names {
+		if v, err := strconv.ParseInt(strings.TrimSpace(h.Get(name)), 10, 64); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return -1
+}
@@ -0,0 +1,98 @@
+// httpdbg/recover.go
+package httpdbg
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// RecoverHandler is server middleware that recovers panics in Handler,
+// logs the offending request with the stack trace, and replies with a
+// 500. The log goes through Debug, so it honours its Output, Logger,
+// Format and Redaction:
+//
+//	http.ListenAndServe(":8080", &httpdbg.RecoverHandler{Handler: mux})
+//
+// Only the part of the request body the handler read before panicking is
+// logged, up to Debug's MaxBodyLog.
+type RecoverHandler struct {
+	Handler http.Handler
+	// Debug formats and writes the log; defaults to a DebugTransport with
+	// default settings
+	Debug *DebugTransport
+	// Response replies to the client after a panic; defaults to a plain
+	// 500 Internal Server Error. It has no effect if the handler had
+	// already started its response.
+	Response func(w http.ResponseWriter, r *http.Request, recovered any)
+}
+
+// ServeHTTP serves r with Handler, recovering any panic.
+// http.ErrAbortHandler is passed on, as it is a deliberate abort.
+func (h *RecoverHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d := h.Debug
+	if d == nil {
+		d = &DebugTransport{}
+	}
+
+	body := d.newCaptureBuffer()
+	defer body.free()
+	if r.Body != nil && r.Body != http.NoBody {
+		r.Body = &teeBody{ReadCloser: r.Body, w: body}
+	}
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		if recovered == http.ErrAbortHandler {
+			panic(recovered)
+		}
+
+		d.logPanic(r, body.Bytes(), recovered, debug.Stack())
+		if h.Response != nil {
+			h.Response(w, r, recovered)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}()
+
+	h.Handler.ServeHTTP(w, r)
+}
+
+// logPanic logs a request whose handler panicked
+func (d *DebugTransport) logPanic(r *http.Request, body []byte, recovered any, stack []byte) {
+	w := d.output(r)
+	x := &exchange{w: w, logger: d.logger(w), redaction: d.redaction(r), req: r}
+
+	if x.logger != nil {
+		fields := []Field{
+			{"method", r.Method},
+			{"url", x.redaction.redactURL(r.URL)},
+			{"remote_addr", r.RemoteAddr},
+			{"request_headers", headerMap(x.redaction.redactHeaders(r.Header))},
+			{"panic", fmt.Sprint(recovered)},
+			{"stack", string(stack)},
+		}
+		if len(body) > 0 {
+			fields = append(fields, Field{"request_body", d.bodyLog(x, nil, r.Header, body, false, false)})
+		}
+		x.logger.Log(r.Context(), LevelError, "http handler panic", fields...)
+		return
+	}
+
+	out, done := d.entry(x)
+	defer done()
+
+	fmt.Fprintln(out, "======= HTTP PANIC =======")
+	fmt.Fprintf(out, "URL: %s %s\n", r.Method, x.redaction.redactURL(r.URL))
+	fmt.Fprintf(out, "Remote: %s\n", r.RemoteAddr)
+	writeHeaders(out, x.redaction.redactHeaders(r.Header))
+	if len(body) > 0 {
+		fmt.Fprintln(out, "\nBody:")
+		fmt.Fprintln(out, d.textBody(x, nil, r.Header, body))
+	}
+	fmt.Fprintf(out, "\nPanic: %v\n\n%s", recovered, stack)
+	fmt.Fprintln(out, "==========================")
+}
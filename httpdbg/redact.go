@@ -0,0 +1,349 @@
+// httpdbg/redact.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// redactedMask replaces secret values in the logs
+const redactedMask = "[REDACTED]"
+
+// DefaultRedactedHeaders are masked unless Redaction.DisableDefaults is set
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+	"X-Amz-Security-Token",
+}
+
+// DefaultRedactedJSONFields are masked in JSON bodies unless
+// Redaction.DisableDefaults is set
+var DefaultRedactedJSONFields = []string{
+	"password",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"client_secret",
+	"api_key",
+}
+
+// DefaultRedactedQueryParams are masked in logged URLs unless
+// Redaction.DisableDefaults is set
+var DefaultRedactedQueryParams = []string{
+	"api_key",
+	"apikey",
+	"token",
+	"access_token",
+	"signature",
+	"sig",
+	"password",
+	"X-Amz-Signature",
+	"X-Amz-Credential",
+}
+
+// Redaction controls which secrets are masked before logging. The zero
+// value masks the default headers and JSON fields in full.
+type Redaction struct {
+	// Headers lists extra header names to mask (case-insensitive)
+	Headers []string
+	// JSONFields lists extra JSON body fields to mask. A plain name
+	// ("password") matches that key at any depth; a dotted path
+	// ("credentials.secret") matches from the root, looking through arrays.
+	JSONFields []string
+	// QueryParams lists extra URL query parameters to mask (case-insensitive)
+	QueryParams []string
+	// GraphQLVariables lists extra GraphQL variables to mask, by name or
+	// dotted path within the variables; JSONFields apply to them too
+	GraphQLVariables []string
+	// DisableDefaults stops the default headers, fields and query
+	// parameters from being masked
+	DisableDefaults bool
+	// Partial keeps the auth scheme and the last four characters of long
+	// values ("Bearer ****9f3a") so different credentials can be told apart
+	Partial bool
+}
+
+// RedactionProfile adds redaction rules for the hosts it matches, since
+// the secrets worth hiding differ from one service to the next
+type RedactionProfile struct {
+	// Host is a glob matched against the host name without port, e.g.
+	// "auth.example.com" or "*.internal"
+	Host string
+	// Redaction's lists are added to those of DebugTransport.Redaction,
+	// and its DisableDefaults and Partial turn those on for the host
+	Redaction Redaction
+	// Replace uses Redaction alone for the host instead of adding to the
+	// transport's
+	Replace bool
+}
+
+// matches reports whether the profile applies to host (as in URL.Host)
+func (p *RedactionProfile) matches(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	ok, _ := path.Match(strings.ToLower(p.Host), strings.ToLower(host))
+	return ok
+}
+
+// merge returns r with the profile's rules added
+func (r Redaction) merge(p *RedactionProfile) Redaction {
+	if p.Replace {
+		return p.Redaction
+	}
+	return Redaction{
+		Headers:          append(append([]string(nil), r.Headers...), p.Redaction.Headers...),
+		JSONFields:       append(append([]string(nil), r.JSONFields...), p.Redaction.JSONFields...),
+		QueryParams:      append(append([]string(nil), r.QueryParams...), p.Redaction.QueryParams...),
+		GraphQLVariables: append(append([]string(nil), r.GraphQLVariables...), p.Redaction.GraphQLVariables...),
+		DisableDefaults:  r.DisableDefaults || p.Redaction.DisableDefaults,
+		Partial:          r.Partial || p.Redaction.Partial,
+	}
+}
+
+// headerRedacted reports whether the named header should be masked
+func (r *Redaction) headerRedacted(name string) bool {
+	if !r.DisableDefaults {
+		for _, h := range DefaultRedactedHeaders {
+			if strings.EqualFold(h, name) {
+				return true
+			}
+		}
+	}
+	for _, h := range r.Headers {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Header returns a copy of h with sensitive values masked, for logging
+// outside DebugTransport, e.g. gRPC metadata
+func (r *Redaction) Header(h http.Header) http.Header {
+	return r.redactHeaders(h)
+}
+
+// Body returns body with sensitive JSON fields masked; bodies that aren't
+// JSON are returned as they are
+func (r *Redaction) Body(body []byte) []byte {
+	return r.redactBody(body)
+}
+
+// redactHeaders returns a copy of h with sensitive values masked; the
+// headers actually sent are never modified
+func (r *Redaction) redactHeaders(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for k, v := range h {
+		if !r.headerRedacted(k) {
+			out[k] = v
+			continue
+		}
+		masked := make([]string, len(v))
+		for i, value := range v {
+			switch http.CanonicalHeaderKey(k) {
+			case "Cookie":
+				masked[i] = r.maskCookies(value)
+			case "Set-Cookie":
+				masked[i] = r.maskSetCookie(value)
+			default:
+				masked[i] = r.mask(value)
+			}
+		}
+		out[k] = masked
+	}
+	return out
+}
+
+// maskCookies masks the values of a Cookie header, keeping the names
+func (r *Redaction) maskCookies(value string) string {
+	pairs := strings.Split(value, ";")
+	for i, pair := range pairs {
+		name, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok {
+			pairs[i] = name + "=" + r.mask(v)
+		} else {
+			pairs[i] = r.mask(name)
+		}
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// maskSetCookie masks the value of a Set-Cookie header, keeping the name
+// and attributes such as Path and Expires
+func (r *Redaction) maskSetCookie(value string) string {
+	first, attrs, _ := strings.Cut(value, ";")
+	name, v, ok := strings.Cut(strings.TrimSpace(first), "=")
+	if !ok {
+		return r.mask(value)
+	}
+	masked := name + "=" + r.mask(v)
+	if attrs != "" {
+		masked += ";" + attrs
+	}
+	return masked
+}
+
+// mask hides a secret value, fully or partially
+func (r *Redaction) mask(value string) string {
+	if !r.Partial {
+		return redactedMask
+	}
+
+	// Keep an auth scheme such as "Bearer" or "Basic" readable
+	scheme, secret := "", value
+	if i := strings.IndexByte(value, ' '); i > 0 {
+		scheme, secret = value[:i+1], value[i+1:]
+	}
+	if len(secret) < 12 {
+		return scheme + "****"
+	}
+	return scheme + "****" + secret[len(secret)-4:]
+}
+
+// redactURL returns u as a string with any password and sensitive query
+// parameter values masked. Parameter order and encoding are otherwise
+// preserved.
+func (r *Redaction) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.Redacted()
+	}
+
+	params := strings.Split(u.RawQuery, "&")
+	for i, param := range params {
+		rawKey, rawValue, ok := strings.Cut(param, "=")
+		if !ok || rawValue == "" {
+			continue
+		}
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			key = rawKey
+		}
+		if !r.queryParamRedacted(key) {
+			continue
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			value = rawValue
+		}
+		// The mask is written unescaped so it reads clearly in the log
+		params[i] = rawKey + "=" + r.mask(value)
+	}
+
+	redacted := *u
+	redacted.RawQuery = strings.Join(params, "&")
+	return redacted.Redacted()
+}
+
+// queryParamRedacted reports whether the named query parameter should be masked
+func (r *Redaction) queryParamRedacted(name string) bool {
+	if !r.DisableDefaults {
+		for _, p := range DefaultRedactedQueryParams {
+			if strings.EqualFold(p, name) {
+				return true
+			}
+		}
+	}
+	for _, p := range r.QueryParams {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody masks sensitive fields in a JSON body, along with any extra
+// fields given. Bodies that are not JSON, or contain nothing to mask, are
+// returned unchanged; otherwise the result is re-encoded compactly.
+func (r *Redaction) redactBody(body []byte, extra ...string) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return body
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+
+	var fields []string
+	if !r.DisableDefaults {
+		fields = append(fields, DefaultRedactedJSONFields...)
+	}
+	fields = append(fields, r.JSONFields...)
+	fields = append(fields, extra...)
+
+	if !r.redactJSON(v, "", fields) {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactJSON masks matching fields of v in place and reports whether
+// anything was masked
+func (r *Redaction) redactJSON(v any, path string, fields []string) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			if jsonFieldRedacted(k, childPath, fields) {
+				v[k] = r.maskJSON(child)
+				changed = true
+				continue
+			}
+			if r.redactJSON(child, childPath, fields) {
+				changed = true
+			}
+		}
+	case []any:
+		// Array elements share their parent's path
+		for _, child := range v {
+			if r.redactJSON(child, path, fields) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// maskJSON masks a JSON value; non-string values are masked whole
+func (r *Redaction) maskJSON(v any) any {
+	if s, ok := v.(string); ok {
+		return r.mask(s)
+	}
+	return redactedMask
+}
+
+// jsonFieldRedacted reports whether a key at the given path is sensitive
+func jsonFieldRedacted(key, path string, fields []string) bool {
+	for _, f := range fields {
+		if strings.Contains(f, ".") {
+			if strings.EqualFold(f, path) {
+				return true
+			}
+		} else if strings.EqualFold(f, key) {
+			return true
+		}
+	}
+	return false
+}
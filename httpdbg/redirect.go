@@ -0,0 +1,48 @@
+// httpdbg/redirect.go
+package httpdbg
+
+import (
+	"net/http"
+)
+
+// redirectHop returns the position of req in a redirect chain and the
+// redirect response that led to it; hop is zero for a request that wasn't
+// redirected. http.Client links each hop to the previous one through
+// Request.Response.
+func redirectHop(req *http.Request) (hop int, from *http.Response) {
+	from = req.Response
+	for r := req; r != nil && r.Response != nil; r = r.Response.Request {
+		hop++
+	}
+	return hop, from
+}
+
+// redirectFields describes the redirect that led to req, if any
+func (d *DebugTransport) redirectFields(req *http.Request) []Field {
+	hop, from := redirectHop(req)
+	if hop == 0 {
+		return nil
+	}
+	fields := []Field{
+		{"redirect_hop", hop},
+		{"redirect_status", from.StatusCode},
+	}
+	if from.Request != nil {
+		fields = append(fields, Field{"redirect_from", d.redaction(req).redactURL(from.Request.URL)})
+	}
+	return fields
+}
+
+// WithMaxRedirects caps how many redirects the client follows; the last
+// redirect response is returned to the caller rather than an error. Zero
+// or less turns off following, so each 3xx is returned as is.
+func WithMaxRedirects(n int) Option {
+	return func(c *clientConfig) {
+		c.client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > n {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// httpdbg/retry.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryTransport retries failed requests with exponential backoff and
+// jitter. Connection errors, 429 and 5xx responses are retried; when the
+// server sends Retry-After or X-RateLimit-Reset, that delay is used
+// instead. Wrap a DebugTransport to log every attempt:
+//
+//	client := &http.Client{Transport: &httpdbg.RetryTransport{
+//		Transport: &httpdbg.DebugTransport{},
+//	}}
+type RetryTransport struct {
+	// Transport performs each attempt; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// MaxAttempts is the total number of tries, defaults to 3
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, defaults to 100ms;
+	// it doubles on each retry up to MaxBackoff (default 10s)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryNonIdempotent also retries requests such as POST
+	RetryNonIdempotent bool
+	// MaxBufferedBody is the largest request body without GetBody that is
+	// buffered so it can be resent, defaults to 1 MiB. Larger bodies, or
+	// any when negative, are sent once without retries.
+	MaxBufferedBody int64
+	// MaxRetryAfter caps how long a server-requested delay is honored,
+	// defaults to one minute; longer delays are cut short to it
+	MaxRetryAfter time.Duration
+
+	mu         sync.Mutex
+	rateLimits map[string]RateLimit
+}
+
+// RateLimit returns the rate-limit state last reported by host, if any
+func (t *RetryTransport) RateLimit(host string) (RateLimit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rl, ok := t.rateLimits[host]
+	return rl, ok
+}
+
+// recordRateLimit remembers the latest rate-limit state for host
+func (t *RetryTransport) recordRateLimit(host string, rl RateLimit) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rateLimits == nil {
+		t.rateLimits = make(map[string]RateLimit)
+	}
+	t.rateLimits[host] = rl
+}
+
+// attemptKey is the context key holding the attempt number
+type attemptKey struct{}
+
+// attemptFrom returns the retry attempt a request belongs to, 0 if unknown
+func attemptFrom(req *http.Request) int {
+	n, _ := req.Context().Value(attemptKey{}).(int)
+	return n
+}
+
+// RoundTrip sends req, retrying as configured
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	attempts := t.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	if !t.retryable(req) {
+		attempts = 1
+	}
+
+	// Each attempt sends a fresh body from GetBody, so one that can only
+	// be read once is buffered first, on a copy of the request
+	if attempts > 1 && req.GetBody == nil && req.Body != nil && req.Body != http.NoBody {
+		buffered := false
+		if max := t.maxBufferedBody(); max >= 0 {
+			req = req.WithContext(req.Context())
+			var err error
+			if buffered, err = bufferBody(req, max); err != nil {
+				return nil, err
+			}
+		}
+		if !buffered {
+			attempts = 1
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		try, err := attemptRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := transport.RoundTrip(try)
+
+		// Prefer the server's own idea of when to try again
+		delay := t.backoff(attempt)
+		if resp != nil {
+			if rl, ok := ParseRateLimit(resp); ok {
+				t.recordRateLimit(req.URL.Host, rl)
+				if wait := rl.Wait(); wait > 0 {
+					delay = min(wait, t.maxRetryAfter())
+				}
+			}
+		}
+
+		if attempt >= attempts || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		// Free the connection before trying again
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryable reports whether req's method allows sending it more than once
+func (t *RetryTransport) retryable(req *http.Request) bool {
+	if t.RetryNonIdempotent {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	// An idempotency key makes a POST safe to repeat
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// maxBufferedBody returns the largest body buffered for retries, or -1
+// for none
+func (t *RetryTransport) maxBufferedBody() int64 {
+	switch {
+	case t.MaxBufferedBody < 0:
+		return -1
+	case t.MaxBufferedBody == 0:
+		return 1 << 20
+	}
+	return t.MaxBufferedBody
+}
+
+// bufferBody reads req's body into memory and sets GetBody to replay it,
+// so the request can be sent again; req should be the caller's own copy.
+// A body already replayable through GetBody is left alone. Bodies larger
+// than max (unless max is zero) are put back together unbuffered, and
+// reported with false.
+func bufferBody(req *http.Request, max int64) (bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true, nil
+	}
+
+	var r io.Reader = req.Body
+	if max > 0 {
+		r = io.LimitReader(req.Body, max+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		req.Body.Close()
+		return false, err
+	}
+	if max > 0 && int64(len(body)) > max {
+		req.Body = &replayBody{Reader: io.MultiReader(bytes.NewReader(body), req.Body), body: req.Body}
+		return false, nil
+	}
+
+	req.Body.Close()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true, nil
+}
+
+// attemptRequest prepares the request for an attempt, with a fresh body
+// from GetBody on retries
+func attemptRequest(req *http.Request, attempt int) (*http.Request, error) {
+	try := req.WithContext(context.WithValue(req.Context(), attemptKey{}, attempt))
+	if attempt > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		try.Body = body
+	}
+	return try, nil
+}
+
+// shouldRetry reports whether an attempt's outcome warrants another try
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// Don't retry once the caller has given up
+		return req.Context().Err() == nil && !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// maxRetryAfter returns the longest server-requested delay honored
+func (t *RetryTransport) maxRetryAfter() time.Duration {
+	if t.MaxRetryAfter > 0 {
+		return t.MaxRetryAfter
+	}
+	return time.Minute
+}
+
+// backoff returns the delay after the given attempt: exponential, capped,
+// with the upper half randomized so clients don't retry in lockstep
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	delay := t.InitialBackoff
+	if delay <= 0 {
+		delay = 100 * time.Millisecond
+	}
+	max := t.MaxBackoff
+	if max <= 0 {
+		max = 10 * time.Second
+	}
+
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,38 @@
+// httpdbg/reverseproxy.go
+package httpdbg
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+)
+
+// NewReverseProxy returns a reverse proxy to target that logs every
+// exchange through d, for debugging clients whose HTTP transport can't be
+// swapped, such as third-party SDKs: point the client at the proxy instead
+// of the real endpoint.
+//
+//	target, _ := url.Parse("https://api.example.com")
+//	http.ListenAndServe("localhost:8080", httpdbg.NewReverseProxy(target, nil))
+//
+// A nil d logs with the defaults. Any faults, e.g. Fault{Rate: 1,
+// Latency: time.Second} to simulate a slow upstream, are injected below
+// d, so the logged durations include them. Requests are sent with the
+// target's Host header.
+func NewReverseProxy(target *url.URL, d *DebugTransport, faults ...Fault) *httputil.ReverseProxy {
+	if d == nil {
+		d = &DebugTransport{}
+	}
+	if len(faults) > 0 {
+		d.Transport = &FaultTransport{Transport: d.Transport, Faults: faults}
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+	}
+	proxy.Transport = d
+	return proxy
+}
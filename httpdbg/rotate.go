@@ -0,0 +1,183 @@
+// httpdbg/rotate.go
+package httpdbg
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RotatingFile is a log file for Output that is rotated once it reaches
+// MaxSize bytes or has been written to for Interval. Rotated files are
+// renamed with a timestamp suffix, e.g. debug.log.20060102T150405.000000000,
+// optionally gzipped, and pruned to the newest MaxBackups. Zero fields turn
+// that behaviour off.
+//
+// Rotation happens between writes, and text entries are several writes
+// unless Async is set, so an entry may occasionally straddle two files.
+type RotatingFile struct {
+	// Path is the file written to; its directory is created if needed
+	Path string
+	// MaxSize is the size in bytes that triggers a rotation
+	MaxSize int64
+	// Interval is how long a file is written to before it is rotated
+	Interval time.Duration
+	// MaxBackups is how many rotated files are kept
+	MaxBackups int
+	// Compress gzips rotated files in the background
+	Compress bool
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	// background tracks compression and pruning, waited for by Close;
+	// backupMu runs them one rotation at a time
+	background sync.WaitGroup
+	backupMu   sync.Mutex
+}
+
+// NewRotatingFile returns a file at path rotated at maxSize bytes
+func NewRotatingFile(path string, maxSize int64) *RotatingFile {
+	return &RotatingFile{Path: path, MaxSize: maxSize}
+}
+
+// Write appends p to the file, rotating it first if it is due
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.due(len(p)) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file and waits for background compression
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.background.Wait()
+	return err
+}
+
+// open opens Path for appending
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size, f.opened = file, info.Size(), time.Now()
+	return nil
+}
+
+// due reports whether the file should be rotated before writing n bytes.
+// An empty file is never rotated, so a write larger than MaxSize still
+// goes somewhere.
+func (f *RotatingFile) due(n int) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.MaxSize > 0 && f.size+int64(n) > f.MaxSize {
+		return true
+	}
+	return f.Interval > 0 && time.Since(f.opened) >= f.Interval
+}
+
+// rotate moves the current file aside and opens a fresh one
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	rotated := f.Path + "." + time.Now().UTC().Format("20060102T150405.000000000")
+	if err := os.Rename(f.Path, rotated); err != nil {
+		return err
+	}
+
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+		f.backupMu.Lock()
+		defer f.backupMu.Unlock()
+		if f.Compress {
+			compressFile(rotated)
+		}
+		f.prune()
+	}()
+
+	return f.open()
+}
+
+// prune removes the oldest rotated files beyond MaxBackups
+func (f *RotatingFile) prune() {
+	if f.MaxBackups <= 0 {
+		return
+	}
+	rotated, err := filepath.Glob(f.Path + ".*")
+	if err != nil || len(rotated) <= f.MaxBackups {
+		return
+	}
+	// Timestamp suffixes sort oldest first
+	sort.Strings(rotated)
+	for _, name := range rotated[:len(rotated)-f.MaxBackups] {
+		os.Remove(name)
+	}
+}
+
+// compressFile replaces path with a gzipped copy, path.gz. On failure the
+// uncompressed file is kept.
+func compressFile(path string) {
+	in, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return
+	}
+	os.Remove(path)
+}
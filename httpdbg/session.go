@@ -0,0 +1,250 @@
+// httpdbg/session.go
+package httpdbg
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session aggregates the exchanges of one or more DebugTransports into a
+// summary: request counts per host, status codes, latency percentiles and
+// bytes transferred. Print it on demand, export Summary, or Close it when
+// the client is done to print the final report:
+//
+//	session := &httpdbg.Session{}
+//	session.Attach(transport)
+//	defer session.Close()
+//
+// Every latency is kept until the session is closed, so it suits test
+// runs and debugging sessions rather than long-lived processes.
+type Session struct {
+	// Output receives the report printed by Close; defaults to os.Stdout
+	Output io.Writer
+
+	mu     sync.Mutex
+	start  time.Time
+	hosts  map[string]*hostStats
+	closed bool
+}
+
+// SessionSummary is a snapshot of a Session. Durations are in nanoseconds
+// when encoded as JSON.
+type SessionSummary struct {
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	// Total covers every host; its Host is empty
+	Total HostSummary `json:"total"`
+	// Hosts are sorted by request count, busiest first
+	Hosts []HostSummary `json:"hosts"`
+}
+
+// HostSummary aggregates the exchanges with one host
+type HostSummary struct {
+	Host     string `json:"host,omitempty"`
+	Requests int    `json:"requests"`
+	// Errors counts requests that got no response
+	Errors int `json:"errors"`
+	// Statuses counts responses by status code
+	Statuses map[int]int `json:"statuses"`
+	// P50, P95 and P99 are latency percentiles, up to the response headers
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+	P99 time.Duration `json:"p99"`
+	// BytesSent and BytesReceived count body bytes
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+}
+
+// hostStats accumulates one host's exchanges
+type hostStats struct {
+	requests, errors int
+	statuses         map[int]int
+	latencies        []time.Duration
+	sent, received   int64
+}
+
+// Attach records every exchange of d from now on
+func (s *Session) Attach(d *DebugTransport) {
+	d.OnCapture(s.Record)
+}
+
+// Record adds an exchange to the session; Attach calls it for each
+// captured exchange
+func (s *Session) Record(c *CapturedExchange) {
+	host := c.URL
+	if u, err := url.Parse(c.URL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if s.hosts == nil {
+		s.start = c.Start
+		s.hosts = make(map[string]*hostStats)
+	}
+	if c.Start.Before(s.start) {
+		s.start = c.Start
+	}
+
+	h := s.hosts[host]
+	if h == nil {
+		h = &hostStats{statuses: make(map[int]int)}
+		s.hosts[host] = h
+	}
+	h.requests++
+	h.latencies = append(h.latencies, c.Duration)
+	h.sent += c.RequestSize
+	if c.Err != nil {
+		h.errors++
+		return
+	}
+	h.statuses[c.Status]++
+	h.received += c.ResponseSize
+}
+
+// Summary returns the session's summary so far
+func (s *Session) Summary() SessionSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := SessionSummary{Start: s.start, Total: HostSummary{Statuses: make(map[int]int)}}
+	if !s.start.IsZero() {
+		summary.Duration = time.Since(s.start)
+	}
+
+	var all []time.Duration
+	for host, h := range s.hosts {
+		hs := HostSummary{
+			Host:          host,
+			Requests:      h.requests,
+			Errors:        h.errors,
+			Statuses:      make(map[int]int, len(h.statuses)),
+			BytesSent:     h.sent,
+			BytesReceived: h.received,
+		}
+		for code, n := range h.statuses {
+			hs.Statuses[code] = n
+			summary.Total.Statuses[code] += n
+		}
+		hs.P50, hs.P95, hs.P99 = percentiles(h.latencies)
+		summary.Hosts = append(summary.Hosts, hs)
+
+		summary.Total.Requests += h.requests
+		summary.Total.Errors += h.errors
+		summary.Total.BytesSent += h.sent
+		summary.Total.BytesReceived += h.received
+		all = append(all, h.latencies...)
+	}
+	summary.Total.P50, summary.Total.P95, summary.Total.P99 = percentiles(all)
+
+	sort.Slice(summary.Hosts, func(i, j int) bool {
+		a, b := summary.Hosts[i], summary.Hosts[j]
+		if a.Requests != b.Requests {
+			return a.Requests > b.Requests
+		}
+		return a.Host < b.Host
+	})
+	return summary
+}
+
+// Print writes the summary so far to w
+func (s *Session) Print(w io.Writer) error {
+	summary := s.Summary()
+
+	var b strings.Builder
+	fmt.Fprintln(&b, "======= HTTP SESSION =======")
+	fmt.Fprintf(&b, "Duration: %s\n", summary.Duration.Round(time.Millisecond))
+	writeHostSummary(&b, "Total", summary.Total)
+	for _, h := range summary.Hosts {
+		fmt.Fprintln(&b)
+		writeHostSummary(&b, "Host "+h.Host, h)
+	}
+	fmt.Fprintln(&b, "============================")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Close prints the final summary to Output. Exchanges finishing after
+// Close are not recorded.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	w := s.Output
+	if w == nil {
+		w = os.Stdout
+	}
+	return s.Print(w)
+}
+
+// writeHostSummary prints one host's lines of the report
+func writeHostSummary(w io.Writer, title string, h HostSummary) {
+	fmt.Fprintf(w, "%s: %d requests, %d errors\n", title, h.Requests, h.Errors)
+	if h.Requests == 0 {
+		return
+	}
+
+	codes := make([]int, 0, len(h.Statuses))
+	for code := range h.Statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	statuses := make([]string, len(codes))
+	for i, code := range codes {
+		statuses[i] = fmt.Sprintf("%d x%d", code, h.Statuses[code])
+	}
+	if len(statuses) > 0 {
+		fmt.Fprintf(w, "  Status: %s\n", strings.Join(statuses, ", "))
+	}
+
+	fmt.Fprintf(w, "  Latency: p50 %s, p95 %s, p99 %s\n",
+		h.P50.Round(time.Microsecond), h.P95.Round(time.Microsecond), h.P99.Round(time.Microsecond))
+	fmt.Fprintf(w, "  Bytes: %s sent, %s received\n", byteSize(h.BytesSent), byteSize(h.BytesReceived))
+}
+
+// percentiles returns the 50th, 95th and 99th percentiles of latencies
+// by nearest rank
+func percentiles(latencies []time.Duration) (p50, p95, p99 time.Duration) {
+	if len(latencies) == 0 {
+		return 0, 0, 0
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := func(p float64) time.Duration {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return sorted[max(i, 0)]
+	}
+	return rank(0.50), rank(0.95), rank(0.99)
+}
+
+// byteSize formats a byte count with a binary unit
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
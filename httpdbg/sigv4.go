@@ -0,0 +1,273 @@
+// httpdbg/sigv4.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSCredentials are the keys used to sign requests
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+}
+
+// EnvAWSCredentials reads credentials from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+func EnvAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return creds, nil
+}
+
+// SigV4Transport signs requests with AWS Signature Version 4 before
+// sending them. The Authorization header and session token are masked by
+// DebugTransport's default redaction.
+type SigV4Transport struct {
+	// Transport sends the signed requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	Region    string
+	Service   string
+	// Credentials provides the signing keys; defaults to EnvAWSCredentials
+	Credentials func(ctx context.Context) (AWSCredentials, error)
+
+	// now is replaceable for signing at a fixed time
+	now func() time.Time
+}
+
+// RoundTrip signs a copy of req and sends it
+func (t *SigV4Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	provider := t.Credentials
+	if provider == nil {
+		provider = EnvAWSCredentials
+	}
+	creds, err := provider(req.Context())
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+
+	signed, err := t.sign(req, creds)
+	if err != nil {
+		closeBody(req)
+		return nil, err
+	}
+	return transport.RoundTrip(signed)
+}
+
+// sign returns a copy of req carrying the SigV4 headers
+func (t *SigV4Transport) sign(req *http.Request, creds AWSCredentials) (*http.Request, error) {
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	at := now().UTC()
+	amzDate := at.Format("20060102T150405Z")
+	date := at.Format("20060102")
+
+	req = req.Clone(req.Context())
+	payloadHash, err := hashBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	if t.Service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	// Sign the host, content type and x-amz-* headers
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = canonicalHeaderValue(v)
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		t.canonicalPath(req),
+		canonicalQuery(req),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, t.Region, t.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, t.Region, t.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature,
+	))
+	return req, nil
+}
+
+// canonicalPath returns the URI-encoded path: each segment is decoded and
+// encoded the RFC 3986 way, and then again for services other than S3,
+// which expect the encoded path to be encoded a second time
+func (t *SigV4Transport) canonicalPath(req *http.Request) string {
+	path := req.URL.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if decoded, err := url.PathUnescape(s); err == nil {
+			s = decoded
+		}
+		s = awsEscape(s)
+		if t.Service != "s3" {
+			s = awsEscape(s)
+		}
+		segments[i] = s
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query sorted by name and value, encoded the
+// way SigV4 requires
+func canonicalQuery(req *http.Request) string {
+	var pairs [][2]string
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			pairs = append(pairs, [2]string{awsEscape(k), awsEscape(v)})
+		}
+	}
+	// By name, then value: sorting the joined strings would put "a-b=1"
+	// before "a=2"
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	params := make([]string, len(pairs))
+	for i, p := range pairs {
+		params[i] = p[0] + "=" + p[1]
+	}
+	return strings.Join(params, "&")
+}
+
+// canonicalHeaderValue joins values with trimmed, single-spaced contents
+func canonicalHeaderValue(values []string) string {
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.Join(strings.Fields(v), " ")
+	}
+	return strings.Join(trimmed, ",")
+}
+
+// awsEscape percent-encodes everything but the RFC 3986 unreserved characters
+func awsEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hashBody returns the hex SHA-256 of req's body, buffering the body so
+// it can still be sent (and replayed through GetBody)
+func hashBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+
+	var body []byte
+	var err error
+	if req.GetBody != nil {
+		var rc io.ReadCloser
+		if rc, err = req.GetBody(); err != nil {
+			return "", err
+		}
+		body, err = io.ReadAll(rc)
+		rc.Close()
+	} else {
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(body), nil
+}
+
+// sha256Hex returns the hex SHA-256 of b
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data under key
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,193 @@
+// httpdbg/sink.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink is a Logger that ships entries to a remote collector. It may
+// buffer them, so close it to send the rest.
+type Sink interface {
+	Logger
+	io.Closer
+}
+
+// Defaults for HTTPSink's zero fields
+const (
+	DefaultSinkBatchSize     = 100
+	DefaultSinkFlushInterval = 5 * time.Second
+)
+
+// HTTPSink posts entries in batches as JSON Lines to URL, for collectors
+// with a bulk ingestion endpoint. A batch is sent when it reaches
+// BatchSize entries or FlushInterval after the last one went. Batches that
+// fail to send are dropped and counted by Dropped.
+type HTTPSink struct {
+	// URL receives the batches as POST requests
+	URL string
+	// Client sends the batches; defaults to http.DefaultClient. Don't use
+	// a debug client, whose own logs would be shipped in turn.
+	Client *http.Client
+	// Header is added to each request, e.g. for an API key
+	Header http.Header
+	// BatchSize defaults to DefaultSinkBatchSize
+	BatchSize int
+	// FlushInterval defaults to DefaultSinkFlushInterval
+	FlushInterval time.Duration
+
+	mu      sync.Mutex
+	batch   bytes.Buffer
+	pending int
+	closed  bool
+	start   sync.Once
+	stop    chan struct{}
+	sending sync.WaitGroup
+	dropped atomic.Int64
+}
+
+// NewHTTPSink returns a sink posting to url
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{URL: url}
+}
+
+// Log adds the entry to the current batch, sending it if it is full
+func (s *HTTPSink) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	s.start.Do(s.startTicker)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return
+	}
+	encodeJSONEntry(&s.batch, level, msg, fields)
+	s.pending++
+
+	if s.pending >= s.batchSize() {
+		body, n := s.take()
+		s.sending.Add(1)
+		go func() {
+			defer s.sending.Done()
+			s.send(body, n)
+		}()
+	}
+}
+
+// Flush sends the current batch and returns any error from doing so
+func (s *HTTPSink) Flush() error {
+	s.mu.Lock()
+	body, n := s.take()
+	s.mu.Unlock()
+	return s.send(body, n)
+}
+
+// Close sends the current batch and waits for batches in flight
+func (s *HTTPSink) Close() error {
+	s.start.Do(s.startTicker)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	err := s.Flush()
+	s.sending.Wait()
+	return err
+}
+
+// Dropped returns how many entries were lost to failed sends
+func (s *HTTPSink) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// startTicker flushes the batch every FlushInterval until Close
+func (s *HTTPSink) startTicker() {
+	interval := s.FlushInterval
+	if interval <= 0 {
+		interval = DefaultSinkFlushInterval
+	}
+	s.stop = make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.Flush()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// batchSize returns the number of entries per batch
+func (s *HTTPSink) batchSize() int {
+	if s.BatchSize <= 0 {
+		return DefaultSinkBatchSize
+	}
+	return s.BatchSize
+}
+
+// take removes the current batch for sending; the caller holds s.mu
+func (s *HTTPSink) take() ([]byte, int) {
+	body := bytes.Clone(s.batch.Bytes())
+	n := s.pending
+	s.batch.Reset()
+	s.pending = 0
+	return body, n
+}
+
+// send posts a batch of n entries
+func (s *HTTPSink) send(body []byte, n int) error {
+	if n == 0 {
+		return nil
+	}
+	err := s.post(body)
+	if err != nil {
+		s.dropped.Add(int64(n))
+	}
+	return err
+}
+
+// post makes the bulk request
+func (s *HTTPSink) post(body []byte) error {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range s.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("log shipping to %s failed: %s", s.URL, resp.Status)
+	}
+	return nil
+}
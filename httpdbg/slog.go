@@ -0,0 +1,60 @@
+// httpdbg/slog.go
+package httpdbg
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// slogLogger adapts a *slog.Logger to the Logger interface
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger returns a Logger writing to l. Header maps become
+// attribute groups.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, f := range fields {
+		if m, ok := f.Value.(map[string]string); ok {
+			attrs = append(attrs, stringGroup(f.Key, m))
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.Key, f.Value))
+	}
+	s.l.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+}
+
+// slogLevel maps a Level onto the slog levels
+func slogLevel(l Level) slog.Level {
+	switch l {
+	case LevelDebug:
+		return slog.LevelDebug
+	case LevelInfo:
+		return slog.LevelInfo
+	case LevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+// stringGroup renders a map as a group of attributes in a stable order
+func stringGroup(name string, m map[string]string) slog.Attr {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]any, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.String(k, m[k]))
+	}
+	return slog.Group(name, attrs...)
+}
@@ -0,0 +1,141 @@
+// httpdbg/soap.go
+package httpdbg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// soapAction returns the action of a SOAP request: the SOAPAction header
+// (SOAP 1.1), the action parameter of its content type (SOAP 1.2), or
+// failing those the name of the first element in the SOAP body. It
+// returns "" for requests that aren't SOAP.
+func soapAction(h http.Header, body []byte) string {
+	if action := strings.Trim(h.Get("SOAPAction"), `"`); action != "" {
+		return action
+	}
+	if mediaType, params, _ := mime.ParseMediaType(h.Get("Content-Type")); mediaType == "application/soap+xml" && params["action"] != "" {
+		return params["action"]
+	}
+	body = decodeBody(h, body)
+	if !isSOAP(h, body) {
+		return ""
+	}
+	_, start, ok := soapBodyElement(body)
+	if !ok {
+		return ""
+	}
+	return start.Name.Local
+}
+
+// soapFault returns "code: reason" for a SOAP response carrying a fault,
+// for either SOAP version, or "" if there is none
+func soapFault(h http.Header, body []byte) string {
+	body = decodeBody(h, body)
+	if !isSOAP(h, body) {
+		return ""
+	}
+
+	dec, start, ok := soapBodyElement(body)
+	if !ok || start.Name.Local != "Fault" {
+		return ""
+	}
+
+	// SOAP 1.1 has faultcode and faultstring; 1.2 has Code/Value and
+	// Reason/Text
+	var code, reason string
+	var path []string
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			path = append(path, t.Name.Local)
+		case xml.EndElement:
+			if len(path) == 0 {
+				return joinFault(code, reason)
+			}
+			path = path[:len(path)-1]
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" {
+				continue
+			}
+			switch strings.Join(path, "/") {
+			case "faultcode", "Code/Value":
+				code = text
+			case "faultstring", "Reason/Text":
+				reason = text
+			}
+		}
+	}
+	return joinFault(code, reason)
+}
+
+// joinFault formats a fault's code and reason
+func joinFault(code, reason string) string {
+	switch {
+	case code == "":
+		return reason
+	case reason == "":
+		return code
+	}
+	return code + ": " + reason
+}
+
+// isSOAP reports whether a decoded body looks like a SOAP envelope
+func isSOAP(h http.Header, body []byte) bool {
+	mediaType, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	if mediaType == "application/soap+xml" {
+		return true
+	}
+	return bodyKind(h.Get("Content-Type"), body) == kindXML && bytes.Contains(body, []byte("Envelope"))
+}
+
+// soapBodyElement finds the first element inside a SOAP envelope's Body
+// and returns the decoder positioned just after its start tag
+func soapBodyElement(body []byte) (*xml.Decoder, xml.StartElement, bool) {
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+
+	var depth int
+	var inBody bool
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, xml.StartElement{}, false
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case depth == 2 && t.Name.Local == "Body":
+				inBody = true
+			case depth == 3 && inBody:
+				return dec, t, true
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				inBody = false
+			}
+			depth--
+		}
+	}
+}
+
+// soapFields returns the structured fields of a SOAP exchange
+func soapFields(action, fault string) []Field {
+	var fields []Field
+	if action != "" {
+		fields = append(fields, Field{"soap_action", action})
+	}
+	if fault != "" {
+		fields = append(fields, Field{"soap_fault", fault})
+	}
+	return fields
+}
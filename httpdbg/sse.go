@@ -0,0 +1,189 @@
+// httpdbg/sse.go
+package httpdbg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxPendingEvent caps how much of an unterminated event is buffered; a
+// longer one is logged as far as it got
+const maxPendingEvent = 1 << 20
+
+// isEventStream reports whether resp is a Server-Sent Events stream
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// sseEvent is one parsed Server-Sent Event
+type sseEvent struct {
+	id, event, data, retry string
+}
+
+// parseEvent parses the lines of one event; comments are skipped
+func parseEvent(raw []byte) sseEvent {
+	var e sseEvent
+	var data [][]byte
+	for _, line := range bytes.Split(raw, []byte("\n")) {
+		if len(line) == 0 || line[0] == ':' {
+			continue
+		}
+		name, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(name) {
+		case "id":
+			e.id = string(value)
+		case "event":
+			e.event = string(value)
+		case "data":
+			data = append(data, value)
+		case "retry":
+			e.retry = string(value)
+		}
+	}
+	e.data = string(bytes.Join(data, []byte("\n")))
+	return e
+}
+
+// sseBody logs a Server-Sent Events stream event by event as the caller
+// reads it, since the stream may never end
+type sseBody struct {
+	io.ReadCloser
+
+	d *DebugTransport
+	x *exchange
+
+	pending []byte
+	events  int
+	size    int64
+	once    sync.Once
+}
+
+// streamEvents logs the response head and returns a body that logs each
+// event as it arrives. The body takes over the exchange's request buffer.
+func (d *DebugTransport) streamEvents(x *exchange, resp *http.Response) io.ReadCloser {
+	duration := time.Since(x.start)
+	if x.logger != nil {
+		d.logStructured(x, resp, nil, 0, duration, nil)
+	} else {
+		w, done := d.entry(x)
+		d.writeResponseHead(w, x, resp, duration)
+		fmt.Fprintln(w, "\nEvents:")
+		done()
+	}
+	return &sseBody{ReadCloser: resp.Body, d: d, x: x}
+}
+
+// Read reads from the underlying body and logs the events completed
+func (s *sseBody) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.size += int64(n)
+		// Events may end lines with CRLF, LF or CR; keep LF only
+		s.pending = append(s.pending, bytes.ReplaceAll(p[:n], []byte("\r"), nil)...)
+		s.logEvents()
+	}
+	if err == io.EOF {
+		s.finish()
+	}
+	return n, err
+}
+
+// Close closes the underlying body and ends the log
+func (s *sseBody) Close() error {
+	err := s.ReadCloser.Close()
+	s.finish()
+	return err
+}
+
+// logEvents logs each complete event in pending
+func (s *sseBody) logEvents() {
+	for {
+		end := bytes.Index(s.pending, []byte("\n\n"))
+		if end < 0 {
+			if len(s.pending) > maxPendingEvent {
+				s.logEvent(s.pending)
+				s.pending = s.pending[:0]
+			}
+			return
+		}
+		s.logEvent(s.pending[:end])
+		s.pending = s.pending[end+2:]
+	}
+}
+
+// logEvent logs one event
+func (s *sseBody) logEvent(raw []byte) {
+	e := parseEvent(raw)
+	if e.id == "" && e.event == "" && e.data == "" && e.retry == "" {
+		// Only comments, such as keep-alives
+		return
+	}
+	s.events++
+
+	d, x := s.d, s.x
+	if x.logger != nil {
+		fields := []Field{{"url", x.redaction.redactURL(x.req.URL)}}
+		fields = append(fields, Field{"exchange", x.seq})
+		if x.id != "" {
+			fields = append(fields, Field{"request_id", x.id})
+		}
+		for _, f := range []Field{{"event_id", e.id}, {"event", e.event}, {"retry", e.retry}} {
+			if f.Value != "" {
+				fields = append(fields, f)
+			}
+		}
+		fields = append(fields, Field{"data", d.bodyLog(x, nil, nil, []byte(e.data), false, false)})
+		x.logger.Log(x.req.Context(), LevelDebug, "sse event", fields...)
+		return
+	}
+
+	w, done := d.entry(x)
+	defer done()
+
+	fmt.Fprint(w, "[event")
+	if e.event != "" {
+		fmt.Fprintf(w, " %s", e.event)
+	}
+	if e.id != "" {
+		fmt.Fprintf(w, " id=%s", e.id)
+	}
+	if e.retry != "" {
+		fmt.Fprintf(w, " retry=%s", e.retry)
+	}
+	fmt.Fprintf(w, "] %s\n", d.textBody(x, nil, nil, []byte(e.data)))
+}
+
+// finish ends the log once
+func (s *sseBody) finish() {
+	s.once.Do(func() {
+		if len(s.pending) > 0 {
+			s.logEvent(s.pending)
+			s.pending = nil
+		}
+		defer s.x.release()
+
+		d, x := s.d, s.x
+		duration := time.Since(x.start)
+		if x.logger != nil {
+			x.logger.Log(x.req.Context(), LevelDebug, "sse stream closed",
+				Field{"url", x.redaction.redactURL(x.req.URL)},
+				Field{"events", s.events},
+				Field{"response_size", s.size},
+				Field{"duration", duration},
+			)
+			return
+		}
+
+		w, done := d.entry(x)
+		defer done()
+		fmt.Fprintf(w, "[stream closed after %s: %d events, %d bytes]\n", duration.Round(time.Millisecond), s.events, s.size)
+		fmt.Fprintln(w, "=============================")
+	})
+}
@@ -0,0 +1,132 @@
+// httpdbg/stream.go
+package httpdbg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamBody tees a response body to the log as the caller reads it.
+// Text output is written chunk by chunk; a structured logger gets one entry
+// once the body is fully read or closed, holding at most MaxBodyLog bytes.
+type streamBody struct {
+	io.ReadCloser
+
+	d    *DebugTransport
+	x    *exchange
+	resp *http.Response
+
+	// echo is false for bodies that are only summarized
+	echo     bool
+	limit    int
+	captured bytes.Buffer
+	size     int64
+	once     sync.Once
+}
+
+// streamResponse logs the response head and returns a body that logs the
+// rest as it is read. The body takes over the exchange's request buffer.
+func (d *DebugTransport) streamResponse(x *exchange, resp *http.Response) io.ReadCloser {
+	contentType := resp.Header.Get("Content-Type")
+	s := &streamBody{
+		ReadCloser: resp.Body,
+		d:          d,
+		x:          x,
+		resp:       resp,
+		echo:       resp.Header.Get("Content-Encoding") == "" && !isBinary(contentType, nil),
+		limit:      d.bodyLimit(),
+	}
+
+	if x.logger == nil {
+		w, done := d.entry(x)
+		d.writeResponseHead(w, x, resp, time.Since(x.start))
+		fmt.Fprintln(w, "\nBody:")
+		done()
+	}
+	return s
+}
+
+// Read reads from the underlying body and logs what was read
+func (s *streamBody) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 {
+		s.record(p[:n])
+	}
+	if err == io.EOF {
+		s.finish()
+	}
+	return n, err
+}
+
+// Close closes the underlying body and completes the log entry
+func (s *streamBody) Close() error {
+	err := s.ReadCloser.Close()
+	s.finish()
+	return err
+}
+
+// record logs the part of chunk that is within the body limit
+func (s *streamBody) record(chunk []byte) {
+	logged := chunk
+	if s.limit >= 0 {
+		remaining := int64(s.limit) - s.size
+		if remaining < 0 {
+			remaining = 0
+		}
+		if int64(len(logged)) > remaining {
+			logged = logged[:remaining]
+		}
+	}
+	s.size += int64(len(chunk))
+
+	if len(logged) == 0 {
+		return
+	}
+	if s.x.logger != nil {
+		s.captured.Write(logged)
+		return
+	}
+	if s.echo {
+		w, done := s.d.entry(s.x)
+		w.Write(logged)
+		done()
+	}
+}
+
+// finish writes the end of the log entry once
+func (s *streamBody) finish() {
+	s.once.Do(func() {
+		duration := time.Since(s.x.start)
+		defer s.x.release()
+
+		if s.x.logger != nil {
+			// A partial body can't be decoded or redacted, so log it only
+			// when it was captured in full
+			var body []byte
+			if int64(s.captured.Len()) == s.size {
+				body = s.captured.Bytes()
+			}
+			s.d.logStructured(s.x, s.resp, body, s.size, duration, nil)
+			return
+		}
+
+		w, done := s.d.entry(s.x)
+		defer done()
+
+		if !s.echo {
+			fmt.Fprintf(w, "[streamed body: %s, %d bytes]\n", s.resp.Header.Get("Content-Type"), s.size)
+		} else if truncated := s.size - int64(s.limit); s.limit >= 0 && truncated > 0 {
+			fmt.Fprintf(w, "... (%d bytes truncated)\n", truncated)
+		} else {
+			fmt.Fprintln(w)
+		}
+		if s.x.timings != nil {
+			fmt.Fprintf(w, "\nTiming: %s\n", s.x.timings)
+		}
+		fmt.Fprintln(w, "=============================")
+	})
+}
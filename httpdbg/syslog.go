@@ -0,0 +1,49 @@
+//go:build !windows && !plan9
+
+// httpdbg/syslog.go
+package httpdbg
+
+import (
+	"bytes"
+	"context"
+	"log/syslog"
+)
+
+// SyslogSink sends each entry as a JSON message to a syslog daemon, at
+// the syslog severity matching its level
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog daemon at raddr over network
+// ("udp", "tcp", or "" for the local daemon), tagging messages with tag
+func NewSyslogSink(network, raddr, tag string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Log sends the entry; syslog delivery errors are ignored
+func (s *SyslogSink) Log(ctx context.Context, level Level, msg string, fields ...Field) {
+	var buf bytes.Buffer
+	encodeJSONEntry(&buf, level, msg, fields)
+	line := string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+
+	switch level {
+	case LevelDebug:
+		s.w.Debug(line)
+	case LevelInfo:
+		s.w.Info(line)
+	case LevelWarn:
+		s.w.Warning(line)
+	default:
+		s.w.Err(line)
+	}
+}
+
+// Close closes the connection to the daemon
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,323 @@
+// httpdbg/throttle.go
+package httpdbg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate is a token-bucket limit: PerSecond (or PerMinute) requests on
+// average, with bursts of up to Burst (default 1). PerSecond wins when
+// both are set; neither means unlimited.
+type Rate struct {
+	PerSecond float64 `json:"per_second,omitempty"`
+	PerMinute float64 `json:"per_minute,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+}
+
+// perSecond returns the average rate in requests per second
+func (r Rate) perSecond() float64 {
+	if r.PerSecond > 0 {
+		return r.PerSecond
+	}
+	return r.PerMinute / 60
+}
+
+// limited reports whether r limits anything
+func (r Rate) limited() bool {
+	return r.perSecond() > 0
+}
+
+// LoadRateProfiles reads per-host rates for RateLimitTransport.Hosts from
+// a JSON file keyed by host (as in URL.Host), e.g.
+//
+//	{"api.example.com": {"per_minute": 600, "burst": 10}}
+func LoadRateProfiles(path string) (map[string]Rate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hosts map[string]Rate
+	if err := json.Unmarshal(data, &hosts); err != nil {
+		return nil, fmt.Errorf("rate profiles %s: %w", path, err)
+	}
+	return hosts, nil
+}
+
+// RateLimitTransport holds requests back to stay under request rates,
+// both overall and per host. Waiting respects the request's context. Rates
+// can be changed while in use with SetGlobalRate and SetHostRate, and
+// WaitReport tells how long each host's requests were held back.
+type RateLimitTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Global limits all requests together
+	Global Rate
+	// PerHost limits each host separately, unless Hosts has an entry for it
+	PerHost Rate
+	// Hosts overrides PerHost for specific hosts (as in URL.Host)
+	Hosts map[string]Rate
+
+	mu      sync.Mutex
+	global  *tokenBucket
+	buckets map[string]*tokenBucket
+	waits   map[string]*HostWait
+}
+
+// HostWait is the time one host's requests spent held back by the rate
+// limits. Durations are in nanoseconds when encoded as JSON.
+type HostWait struct {
+	Host     string `json:"host"`
+	Requests int    `json:"requests"`
+	// Throttled counts the requests that had to wait
+	Throttled int           `json:"throttled"`
+	Total     time.Duration `json:"total"`
+	Max       time.Duration `json:"max"`
+}
+
+// RoundTrip waits for the global and host buckets, then sends req
+func (t *RateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	global, host := t.bucketsFor(req.URL.Host)
+	start := time.Now()
+	err := global.wait(req.Context())
+	if err == nil {
+		if err = host.wait(req.Context()); err != nil && global != nil {
+			// The request won't be sent, so its global token goes back
+			global.cancel(1)
+		}
+	}
+	t.recordWait(req.URL.Host, time.Since(start))
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+// SetGlobalRate changes the overall limit for requests from now on
+func (t *RateLimitTransport) SetGlobalRate(rate Rate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.Global = rate
+	if t.global == nil {
+		return
+	}
+	if rate.limited() {
+		t.global.setRate(rate)
+	} else {
+		t.global = nil
+	}
+}
+
+// SetHostRate changes the limit for requests to host (as in URL.Host)
+// from now on, adding it to Hosts
+func (t *RateLimitTransport) SetHostRate(host string, rate Rate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	hosts := make(map[string]Rate, len(t.Hosts)+1)
+	for h, r := range t.Hosts {
+		hosts[h] = r
+	}
+	hosts[host] = rate
+	t.Hosts = hosts
+
+	if b, ok := t.buckets[host]; ok {
+		if rate.limited() {
+			b.setRate(rate)
+		} else {
+			delete(t.buckets, host)
+		}
+	}
+}
+
+// recordWait adds a request's wait to its host's totals
+func (t *RateLimitTransport) recordWait(host string, wait time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.waits == nil {
+		t.waits = make(map[string]*HostWait)
+	}
+	w := t.waits[host]
+	if w == nil {
+		w = &HostWait{Host: host}
+		t.waits[host] = w
+	}
+	w.Requests++
+	// Scheduling noise isn't throttling
+	if wait < time.Millisecond {
+		return
+	}
+	w.Throttled++
+	w.Total += wait
+	w.Max = max(w.Max, wait)
+}
+
+// WaitReport returns each host's wait so far, longest total first
+func (t *RateLimitTransport) WaitReport() []HostWait {
+	t.mu.Lock()
+	report := make([]HostWait, 0, len(t.waits))
+	for _, w := range t.waits {
+		report = append(report, *w)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.Host < b.Host
+	})
+	return report
+}
+
+// PrintWaitReport writes the wait report to w
+func (t *RateLimitTransport) PrintWaitReport(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintln(&b, "======= RATE LIMIT WAITS =======")
+	for _, h := range t.WaitReport() {
+		fmt.Fprintf(&b, "Host %s: %d requests, %d throttled, waited %s (max %s)\n",
+			h.Host, h.Requests, h.Throttled, h.Total.Round(time.Millisecond), h.Max.Round(time.Millisecond))
+	}
+	fmt.Fprintln(&b, "================================")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// bucketsFor returns the global bucket and the one for host; either is
+// nil when unlimited
+func (t *RateLimitTransport) bucketsFor(host string) (*tokenBucket, *tokenBucket) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.global == nil && t.Global.limited() {
+		t.global = newTokenBucket(t.Global)
+	}
+
+	rate, ok := t.Hosts[host]
+	if !ok {
+		rate = t.PerHost
+	}
+	if !rate.limited() {
+		return t.global, nil
+	}
+
+	if t.buckets == nil {
+		t.buckets = make(map[string]*tokenBucket)
+	}
+	b, ok := t.buckets[host]
+	if !ok {
+		b = newTokenBucket(rate)
+		t.buckets[host] = b
+	}
+	return t.global, b
+}
+
+// tokenBucket is a token bucket that hands out tokens in arrival order by
+// letting the balance go negative: each caller reserves a token and sleeps
+// until it would have been available
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   Rate
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns a full bucket for rate
+func newTokenBucket(rate Rate) *tokenBucket {
+	rate = bucketRate(rate)
+	return &tokenBucket{rate: rate, tokens: float64(rate.Burst), last: time.Now()}
+}
+
+// bucketRate returns rate as a bucket uses it: per second, with a burst
+// of at least 1
+func bucketRate(rate Rate) Rate {
+	return Rate{PerSecond: rate.perSecond(), Burst: max(rate.Burst, 1)}
+}
+
+// setRate switches the bucket to rate, keeping the tokens it has earned
+// up to the new burst. Callers already waiting keep their wait.
+func (b *tokenBucket) setRate(rate Rate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.rate = bucketRate(rate)
+	b.tokens = min(b.tokens, float64(b.rate.Burst))
+}
+
+// refill adds the tokens earned since the last refill
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate.PerSecond
+	if max := float64(b.rate.Burst); b.tokens > max {
+		b.tokens = max
+	}
+	b.last = now
+}
+
+// wait takes a token, sleeping until it is available or ctx is done. A
+// nil bucket never waits.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	return b.waitN(ctx, 1)
+}
+
+// waitN takes n tokens, as wait does
+func (b *tokenBucket) waitN(ctx context.Context, n float64) error {
+	if b == nil {
+		return nil
+	}
+
+	delay := b.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		b.cancel(n)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes n tokens and returns how long until they are covered
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill(time.Now())
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate.PerSecond * float64(time.Second))
+}
+
+// cancel returns n reserved tokens that were not used
+func (b *tokenBucket) cancel(n float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += n
+}
@@ -0,0 +1,132 @@
+// httpdbg/timeout.go
+package httpdbg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrHeaderTimeout is returned, wrapped, when response headers don't
+// arrive within the header timeout
+var ErrHeaderTimeout = errors.New("timeout awaiting response headers")
+
+// timeoutKey and headerTimeoutKey are the context keys for per-request
+// timeout overrides
+type (
+	timeoutKey       struct{}
+	headerTimeoutKey struct{}
+)
+
+// WithRequestTimeout returns a context that overrides TimeoutTransport's
+// Timeout for requests made with it; zero disables the timeout
+func WithRequestTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// WithHeaderTimeout returns a context that overrides TimeoutTransport's
+// HeaderTimeout for requests made with it; zero disables the timeout
+func WithHeaderTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, headerTimeoutKey{}, timeout)
+}
+
+// TimeoutTransport bounds how long requests may take. HeaderTimeout
+// limits the wait for response headers; Timeout limits the whole exchange,
+// including reading the body. Either can be overridden per request with
+// WithHeaderTimeout and WithRequestTimeout.
+type TimeoutTransport struct {
+	// Transport sends the requests; defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// HeaderTimeout is the default time allowed until headers arrive
+	HeaderTimeout time.Duration
+	// Timeout is the default time allowed for the full exchange
+	Timeout time.Duration
+}
+
+// RoundTrip sends req under its timeouts
+func (t *TimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	timeout := t.Timeout
+	if v, ok := req.Context().Value(timeoutKey{}).(time.Duration); ok {
+		timeout = v
+	}
+	headerTimeout := t.HeaderTimeout
+	if v, ok := req.Context().Value(headerTimeoutKey{}).(time.Duration); ok {
+		headerTimeout = v
+	}
+	if timeout <= 0 && headerTimeout <= 0 {
+		return transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancelCause(req.Context())
+	var timers []*time.Timer
+	if timeout > 0 {
+		timers = append(timers, time.AfterFunc(timeout, func() {
+			cancel(fmt.Errorf("request timeout after %s: %w", timeout, context.DeadlineExceeded))
+		}))
+	}
+	var headerTimer *time.Timer
+	if headerTimeout > 0 {
+		headerTimer = time.AfterFunc(headerTimeout, func() {
+			cancel(fmt.Errorf("%w after %s", ErrHeaderTimeout, headerTimeout))
+		})
+	}
+	stop := func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+		cancel(nil)
+	}
+
+	resp, err := transport.RoundTrip(req.WithContext(ctx))
+	if headerTimer != nil {
+		headerTimer.Stop()
+	}
+	if err != nil {
+		// Report which timeout fired rather than a bare cancellation
+		if cause := context.Cause(ctx); cause != nil && req.Context().Err() == nil {
+			err = cause
+		}
+		stop()
+		return nil, err
+	}
+
+	// The body is read under the overall timeout
+	resp.Body = &cancelBody{ReadCloser: resp.Body, ctx: ctx, stop: stop}
+	return resp, nil
+}
+
+// cancelBody stops a request's timers when its body is closed and reports
+// the timeout that interrupted a read
+type cancelBody struct {
+	io.ReadCloser
+	ctx  context.Context
+	stop func()
+	once sync.Once
+}
+
+// Read reads the body, replacing a cancellation error with its cause
+func (b *cancelBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF && b.ctx.Err() != nil {
+		if cause := context.Cause(b.ctx); cause != nil {
+			err = cause
+		}
+	}
+	return n, err
+}
+
+// Close closes the body and releases the timers
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.stop)
+	return err
+}
@@ -0,0 +1,83 @@
+// httpdbg/tlsconfig.go
+package httpdbg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// insecureWarning is logged with requests whose certificates aren't verified
+const insecureWarning = "WARNING: TLS certificate verification is disabled (InsecureSkipVerify)"
+
+// tlsConfig returns the underlying transport's TLS configuration for
+// options to modify
+func (c *clientConfig) tlsConfig() *tls.Config {
+	return tlsClientConfig(c.transport())
+}
+
+// WithClientCertificate presents cert to servers that ask for one, for
+// mutual TLS. Load it with tls.LoadX509KeyPair.
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *clientConfig) {
+		cfg := c.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithRootCAs verifies servers against pool instead of the system roots
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(c *clientConfig) {
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithServerName sets the name sent in SNI and checked against the
+// server's certificate, for reaching a host by IP or through a tunnel
+func WithServerName(name string) Option {
+	return func(c *clientConfig) {
+		c.tlsConfig().ServerName = name
+	}
+}
+
+// WithInsecureSkipVerify turns off server certificate verification,
+// leaving connections open to interception. Only use it against test
+// servers; every HTTPS request is logged with a warning while it is on.
+func WithInsecureSkipVerify() Option {
+	return func(c *clientConfig) {
+		c.tlsConfig().InsecureSkipVerify = true
+	}
+}
+
+// LoadCertPool reads PEM-encoded CA certificates from files into a pool
+// for WithRootCAs
+func LoadCertPool(files ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		pem, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// insecure reports whether req goes out over TLS without certificate
+// verification; only an *http.Transport underneath, possibly behind a
+// HostTransport, can be checked
+func (d *DebugTransport) insecure(req *http.Request) bool {
+	if req.URL.Scheme != "https" {
+		return false
+	}
+	rt := d.Transport
+	if h, ok := rt.(*HostTransport); ok {
+		rt = h.Transport
+	}
+	t, ok := rt.(*http.Transport)
+	return ok && t.TLSClientConfig != nil && t.TLSClientConfig.InsecureSkipVerify
+}
@@ -0,0 +1,92 @@
+// httpdbg/tlsinfo.go
+package httpdbg
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultCertExpiryWarning is the expiry warning window used when
+// CertExpiryWarning is zero
+const DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// certExpiryWarning returns the window within which expiring certificates
+// are flagged
+func (d *DebugTransport) certExpiryWarning() time.Duration {
+	if d.CertExpiryWarning == 0 {
+		return DefaultCertExpiryWarning
+	}
+	return d.CertExpiryWarning
+}
+
+// certExpiring reports whether any certificate in the chain expires
+// within the warning window
+func (d *DebugTransport) certExpiring(state *tls.ConnectionState) bool {
+	deadline := time.Now().Add(d.certExpiryWarning())
+	for _, cert := range state.PeerCertificates {
+		if cert.NotAfter.Before(deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeTLS prints the connection's TLS parameters and certificate chain
+func (d *DebugTransport) writeTLS(w io.Writer, state *tls.ConnectionState) {
+	fmt.Fprintf(w, "TLS: %s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		fmt.Fprintf(w, ", ALPN %s", state.NegotiatedProtocol)
+	}
+	fmt.Fprintln(w)
+
+	now := time.Now()
+	for i, cert := range state.PeerCertificates {
+		fmt.Fprintf(w, "Certificate %d: %s, issuer %s, expires %s", i, cert.Subject, cert.Issuer, cert.NotAfter.Format(time.RFC3339))
+		if left := cert.NotAfter.Sub(now); left <= 0 {
+			fmt.Fprint(w, " [EXPIRED]")
+		} else if left < d.certExpiryWarning() {
+			fmt.Fprintf(w, " [EXPIRES IN %s]", expiryText(left))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// expiryText formats the time left on a certificate in days, or finer
+// when it is under a day
+func expiryText(left time.Duration) string {
+	if days := int(left / (24 * time.Hour)); days > 0 {
+		return fmt.Sprintf("%d DAYS", days)
+	}
+	return left.Round(time.Minute).String()
+}
+
+// tlsFields returns the connection's TLS parameters and certificate chain
+// as structured log fields
+func (d *DebugTransport) tlsFields(state *tls.ConnectionState) []Field {
+	certs := make([]map[string]string, len(state.PeerCertificates))
+	for i, cert := range state.PeerCertificates {
+		certs[i] = certInfo(cert)
+	}
+
+	fields := []Field{
+		{"tls_version", tls.VersionName(state.Version)},
+		{"tls_cipher", tls.CipherSuiteName(state.CipherSuite)},
+		{"tls_certificates", certs},
+	}
+	if state.NegotiatedProtocol != "" {
+		fields = append(fields, Field{"tls_alpn", state.NegotiatedProtocol})
+	}
+	return fields
+}
+
+// certInfo summarizes a certificate for logging
+func certInfo(cert *x509.Certificate) map[string]string {
+	return map[string]string{
+		"subject":   cert.Subject.String(),
+		"issuer":    cert.Issuer.String(),
+		"not_after": cert.NotAfter.Format(time.RFC3339),
+	}
+}
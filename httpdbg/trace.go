@@ -0,0 +1,107 @@
+// httpdbg/trace.go
+package httpdbg
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+)
+
+// phaseTimings records when each phase of a request happened, via httptrace
+type phaseTimings struct {
+	mu sync.Mutex
+
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	firstByte    time.Time
+	reused       bool
+}
+
+// newPhaseTimings starts timing a request
+func newPhaseTimings() *phaseTimings {
+	return &phaseTimings{start: time.Now()}
+}
+
+// clientTrace returns the hooks that fill in t. Dialing may happen on
+// another goroutine, and with several addresses more than one connect
+// can be attempted, so the first start and last finish are kept.
+func (t *phaseTimings) clientTrace() *httptrace.ClientTrace {
+	mark := func(field *time.Time, first bool) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !first || field.IsZero() {
+			*field = time.Now()
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&t.dnsStart, true) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { mark(&t.dnsDone, false) },
+		ConnectStart:      func(string, string) { mark(&t.connectStart, true) },
+		ConnectDone:       func(string, string, error) { mark(&t.connectDone, false) },
+		TLSHandshakeStart: func() { mark(&t.tlsStart, true) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { mark(&t.tlsDone, false) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.reused = info.Reused
+		},
+		GotFirstResponseByte: func() { mark(&t.firstByte, true) },
+	}
+}
+
+// phases returns the name and duration of each phase that happened,
+// ending with the total up to now
+func (t *phaseTimings) phases() []Field {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var phases []Field
+	add := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			phases = append(phases, Field{name, to.Sub(from)})
+		}
+	}
+	add("dns", t.dnsStart, t.dnsDone)
+	add("connect", t.connectStart, t.connectDone)
+	add("tls", t.tlsStart, t.tlsDone)
+	add("ttfb", t.start, t.firstByte)
+	phases = append(phases, Field{"total", time.Since(t.start)})
+	return phases
+}
+
+// fields returns the timings as structured log fields
+func (t *phaseTimings) fields() []Field {
+	phases := t.phases()
+	fields := make([]Field, 0, len(phases)+1)
+	for _, p := range phases {
+		fields = append(fields, Field{"timing_" + p.Key, p.Value})
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append(fields, Field{"conn_reused", t.reused})
+}
+
+// String formats the timings for the text output
+func (t *phaseTimings) String() string {
+	var parts []string
+	for _, p := range t.phases() {
+		parts = append(parts, fmt.Sprintf("%s=%s", p.Key, p.Value.(time.Duration).Round(time.Microsecond)))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reused {
+		parts = append(parts, "(reused connection)")
+	}
+	return strings.Join(parts, " ")
+}
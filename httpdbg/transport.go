@@ -2,100 +2,677 @@
 package httpdbg
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // DebugTransport is a custom RoundTripper that logs detailed request and response information
 type DebugTransport struct {
-	// mu prevents concurrent writes to stdout
+	// mu prevents concurrent writes to the output
 	mu sync.Mutex
+	// hooks are the OnRequest and OnResponse callbacks, guarded by hookMu
+	hookMu sync.RWMutex
+	hooks  hooks
+	// async is the background writer of Async mode, started once
+	asyncOnce sync.Once
+	async     *asyncLog
+	// repeats tracks requests for CollapseWindow
+	repeats repeats
+	// seq numbers the exchanges
+	seq atomic.Uint64
+	// memory counts captured body bytes for MaxCaptureMemory
+	memory captureBudget
 	// Transport is the underlying RoundTripper to use
 	Transport http.RoundTripper
+	// Output receives the logs; defaults to os.Stdout
+	Output io.Writer
+	// Logger, when set, receives one structured entry per exchange
+	// instead of the text dump
+	Logger Logger
+	// Slog is a shortcut for Logger: NewSlogLogger(Slog)
+	Slog *slog.Logger
+	// Format selects text or JSON Lines output when neither Logger nor
+	// Slog is set; bodies are truncated to MaxBodyLog in both
+	Format Format
+	// Redaction masks secrets in the logged headers, URLs and JSON bodies
+	Redaction Redaction
+	// HostRedaction overrides Redaction for specific hosts (as in URL.Host)
+	HostRedaction map[string]Redaction
+	// RedactionProfiles add to Redaction for the hosts they match, in
+	// order; a HostRedaction entry takes precedence over them
+	RedactionProfiles []RedactionProfile
+	// MaxBodyLog caps how many bytes of each body are logged; defaults to
+	// DefaultMaxBodyLog, negative logs bodies in full. The body passed on
+	// to the caller is never truncated. Request bodies larger than this,
+	// or of unknown length, are copied as they are sent rather than read
+	// up front, so uploads keep streaming; unless GetBody can replay them,
+	// such bodies are logged after the response arrives. Request bodies
+	// larger than this are left out of the log, as they can't be redacted.
+	MaxBodyLog int
+	// HexPreview is how many leading bytes of a binary body are hex dumped
+	// alongside its summary; zero logs the summary only
+	HexPreview int
+	// Stream logs response bodies as the caller reads them rather than
+	// buffering them first, for large downloads and event streams. Streamed
+	// bodies are logged raw: JSON field redaction does not apply, and
+	// compressed or binary bodies are only summarized. Event streams
+	// (text/event-stream) are always logged event by event as they arrive.
+	Stream bool
+	// BodyDecoder renders the binary bodies it recognizes, such as
+	// protobuf messages (see httpdbg/protodbg), as text in place of the
+	// binary summary
+	BodyDecoder BodyDecoder
+	// Curl adds an equivalent curl command to each logged request. Binary
+	// bodies are referenced as @body.bin rather than inlined.
+	Curl bool
+	// Pretty indents JSON and XML bodies in the text output and colors
+	// them when Output is a terminal
+	Pretty bool
+	// SOAP logs the action of each SOAP request and the code and reason
+	// of any fault in the response, so legacy API calls can be told apart
+	// at a glance
+	SOAP bool
+	// NoColor turns off Pretty's colors even on a terminal; they are also
+	// off when the NO_COLOR environment variable is set
+	NoColor bool
+	// Trace records DNS, connect, TLS handshake, time-to-first-byte and
+	// total timings with net/http/httptrace and logs them with each response
+	Trace bool
+	// ConnInfo logs how each request got its connection: reused from the
+	// pool (and how long it sat idle there) or newly dialed, how long it
+	// waited for it and the local and remote addresses. Long waits point
+	// at an exhausted pool; new dials where reuse was expected at
+	// keep-alive problems.
+	ConnInfo bool
+	// SlowThreshold flags responses that took longer than this to arrive
+	// with a SLOW marker (a warning in structured logs); zero disables it
+	SlowThreshold time.Duration
+	// RequestID adds a generated ID header to requests that lack one and
+	// prints the ID in both the request and response logs, so concurrent
+	// exchanges can be matched up
+	RequestID bool
+	// RequestIDHeader names the request ID header; defaults to X-Request-ID
+	RequestIDHeader string
+	// GroupExchanges holds each request block back and writes it together
+	// with its response or error, so concurrent exchanges don't interleave;
+	// streamed bodies are still written as they arrive. Each exchange is
+	// numbered in its blocks either way.
+	GroupExchanges bool
+	// Filter limits logging to the requests of interest; by default every
+	// request is logged
+	Filter Filter
+	// OnlyErrors holds back each exchange until its outcome is known and
+	// logs it only if the transport failed or the status is 400 or above
+	OnlyErrors bool
+	// SampleRate is the fraction of requests logged, e.g. 0.01 for 1%.
+	// Requests left out are still logged if they fail or get a status of
+	// 400 or above. Zero (or 1 and above) logs every request.
+	SampleRate float64
+	// TLSInfo logs the negotiated TLS version, cipher suite, ALPN protocol
+	// and the server's certificate chain with each HTTPS response
+	TLSInfo bool
+	// CertExpiryWarning flags certificates in a logged chain that expire
+	// within this long (a warning in structured logs); defaults to
+	// DefaultCertExpiryWarning
+	CertExpiryWarning time.Duration
+	// Async writes logs from a background goroutine, so slow output
+	// doesn't hold up requests. Entries wait in a queue of AsyncQueue
+	// entries (default DefaultAsyncQueue); when it is full they are
+	// dropped and counted by Dropped. Call Flush or Close to write out
+	// what is queued.
+	Async      bool
+	AsyncQueue int
+	// CollapseWindow holds back requests identical to one logged within
+	// this long (same method, URL and body), unless they fail or get a
+	// status of 400 or above. The next time the request is logged it
+	// notes how many repeats were held back, so polling loops don't flood
+	// the output. Zero logs every repeat.
+	CollapseWindow time.Duration
+	// MaxCaptureMemory caps the bytes of bodies held for logging and
+	// OnCapture across all exchanges in flight. Once it is reached,
+	// exchanges are logged with their headers only, and their bodies
+	// passed through untouched, until memory is released. Zero means no
+	// cap.
+	MaxCaptureMemory int64
 }
 
-// RoundTrip implements the RoundTripper interface for detailed logging
+// DefaultMaxBodyLog is the body logging limit used when MaxBodyLog is zero
+const DefaultMaxBodyLog = 64 << 10
+
+// outputKey is the context key for per-request output overrides
+type outputKey struct{}
+
+// WithOutput returns a context that sends the logs of requests made with
+// it to w instead of the transport's Output
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputKey{}, w)
+}
+
+// debugKey is the context key for per-request logging toggles
+type debugKey struct{}
+
+// WithDebug returns a context that turns logging on or off for requests
+// made with it. Enabled requests are logged in full regardless of Filter,
+// SampleRate and OnlyErrors; disabled ones pass straight through.
+func WithDebug(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, debugKey{}, enabled)
+}
+
+// debugOverride returns the request's WithDebug setting, if any
+func debugOverride(req *http.Request) (enabled, set bool) {
+	enabled, set = req.Context().Value(debugKey{}).(bool)
+	return enabled, set
+}
+
+// output picks the writer for a request: context override, then Output, then stdout
+func (d *DebugTransport) output(req *http.Request) io.Writer {
+	return d.outputContext(req.Context())
+}
+
+// outputContext picks the writer for ctx, as output does
+func (d *DebugTransport) outputContext(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok && w != nil {
+		return w
+	}
+	if d.Output != nil {
+		return d.Output
+	}
+	return os.Stdout
+}
+
+// RoundTrip implements the RoundTripper interface for detailed logging.
+// Failures of logged requests are returned as a DNSError, ConnectTimeout,
+// TLSHandshakeError, ResponseTimeout or ProxyError where they fit,
+// wrapping the original error.
 func (d *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := d.registeredHooks()
+	if len(h.onRequest) == 0 && len(h.onResponse) == 0 && len(h.onCapture) == 0 {
+		return d.roundTrip(req)
+	}
+
+	// Hooks may modify the request, so they get a copy
+	if len(h.onRequest) > 0 {
+		req = req.Clone(req.Context())
+		for _, fn := range h.onRequest {
+			fn(req)
+		}
+	}
+
+	var c *capturing
+	if len(h.onCapture) > 0 {
+		req, c = d.captureRequest(req, h.onCapture)
+	}
+
+	start := time.Now()
+	resp, err := d.roundTrip(req)
+	duration := time.Since(start)
+	for _, fn := range h.onResponse {
+		fn(resp, duration, err)
+	}
+	if c != nil {
+		resp = d.captureResponse(c, resp, duration, err)
+	}
+	return resp, err
+}
+
+// roundTrip logs and sends req
+func (d *DebugTransport) roundTrip(req *http.Request) (*http.Response, error) {
 	// Use the default transport if none is provided
 	transport := d.Transport
 	if transport == nil {
 		transport = http.DefaultTransport
 	}
 
-	// Clone the request body for logging (as it can only be read once)
-	var requestBody []byte
-	if req.Body != nil {
-		requestBody, _ = io.ReadAll(req.Body)
-		req.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+	// Pass requests that aren't of interest straight through
+	forced, overridden := debugOverride(req)
+	if (overridden && !forced) || (!overridden && !d.Filter.Allows(req)) {
+		return transport.RoundTrip(req)
+	}
+
+	// Tag the request so its log entries can be matched up
+	var id string
+	if d.RequestID {
+		req, id = d.ensureRequestID(req)
+	}
+
+	// Copy the request body for logging (as it can only be read once)
+	var reqBuf *pooledBuffer
+	var reqTee *limitedBuffer
+	req, reqBuf, reqTee = d.copyRequestBody(req)
+
+	w := d.output(req)
+	x := &exchange{w: w, logger: d.logger(w), id: id, seq: d.seq.Add(1), redaction: d.redaction(req), reqBuf: reqBuf, reqTee: reqTee}
+	x.reqSkipped = reqBuf == nil && reqTee == nil && req.Body != nil && req.Body != http.NoBody
+	if d.Async && x.logger != nil {
+		x.logger = asyncLogger{d: d, l: x.logger}
+	}
+
+	// Note the proxy used, when the underlying transport reports it
+	var ctx context.Context
+	ctx, x.proxy = withProxyUse(req.Context())
+	req = req.WithContext(ctx)
+
+	// Follow the request's progress, to place a failure
+	x.phase = &phaseTracker{}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), x.phase.clientTrace()))
+
+	// Time each phase of the request
+	if d.Trace {
+		x.timings = newPhaseTimings()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), x.timings.clientTrace()))
+	}
+
+	// Note how the connection was obtained
+	if d.ConnInfo {
+		x.conn = &connUse{}
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), x.conn.clientTrace()))
 	}
+	x.req = req
+	x.graphQL = parseGraphQL(req, reqBuf.Bytes(), x.redaction)
 
-	// Dump the request details
-	d.logRequest(req, requestBody)
+	// Requests that weren't sampled are only logged if they go wrong
+	errorsOnly := !forced && (d.OnlyErrors || !d.sampled())
+
+	// Repeats of a recently logged request are treated the same way.
+	// Bodies copied as they are sent aren't known yet, so those requests
+	// are never collapsed.
+	var key collapseKey
+	var collapsed bool
+	if d.CollapseWindow > 0 && !forced && reqTee == nil && !x.reqSkipped {
+		key = newCollapseKey(req, reqBuf.Bytes())
+		collapsed, x.repeated = d.collapse(key)
+		errorsOnly = errorsOnly || collapsed
+	}
+
+	// Dump the request details, unless that waits for the outcome or for
+	// the body to be sent
+	if x.logger == nil && !errorsOnly && reqTee == nil {
+		d.logRequest(x)
+	}
 
 	// Perform the actual request
+	x.start = time.Now()
 	resp, err := transport.RoundTrip(req)
+	duration := time.Since(x.start)
+	if x.logger == nil && !errorsOnly && reqTee != nil {
+		d.logRequest(x)
+	}
 	if err != nil {
+		err = classifyError(x, x.phase.phase(), err)
+		if x.logger != nil {
+			d.logStructured(x, nil, nil, 0, duration, err)
+		} else {
+			if errorsOnly {
+				d.logRequest(x)
+			}
+			d.logError(x, err, duration)
+		}
+		x.release()
 		return nil, err
 	}
 
-	// Clone the response body for logging
-	responseBody, _ := io.ReadAll(resp.Body)
-	resp.Body = io.NopCloser(bytes.NewBuffer(responseBody))
+	// Successful exchanges stay quiet in OnlyErrors mode
+	if errorsOnly {
+		if resp.StatusCode < 400 {
+			if collapsed {
+				d.countRepeat(key)
+			}
+			x.release()
+			return resp, nil
+		}
+		if x.logger == nil {
+			d.logRequest(x)
+		}
+	}
+
+	// Event streams may never end, so their events are logged as they
+	// arrive rather than buffered
+	if isEventStream(resp) {
+		resp.Body = d.streamEvents(x, resp)
+		return resp, nil
+	}
+
+	// Log the response body as the caller reads it instead of buffering it
+	if d.Stream {
+		resp.Body = d.streamResponse(x, resp)
+		return resp, nil
+	}
+	defer x.release()
+
+	// Clone the response body for logging; the caller releases the buffer
+	// by closing the body
+	var respBuf *pooledBuffer
+	respBuf, resp.Body = d.captureBody(resp.Body, 1)
+	responseBody := respBuf.Bytes()
+	x.respSkipped = respBuf == nil
 
 	// Dump the response details
-	d.logResponse(resp, responseBody)
+	if x.logger != nil {
+		d.logStructured(x, resp, responseBody, int64(len(responseBody)), duration, nil)
+	} else {
+		d.logResponse(x, resp, responseBody, duration)
+	}
 
 	return resp, nil
 }
 
-// logRequest prints detailed information about the outgoing HTTP request
-func (d *DebugTransport) logRequest(req *http.Request, body []byte) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+// copyRequestBody arranges for req's body to be logged, returning the
+// request to send. A body that GetBody can replay is copied from there,
+// up to the body logging limit, and sent untouched. Other bodies of known
+// length within the limit are read up front into a buffer shared by the
+// transport, which may read it after RoundTrip returns, and the logging,
+// and which GetBody replays.
+// Larger uploads, and those of unknown length, are streamed: what is sent
+// is copied up to the limit as it goes.
+func (d *DebugTransport) copyRequestBody(req *http.Request) (*http.Request, *pooledBuffer, *limitedBuffer) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, nil, nil
+	}
+	limit := d.bodyLimit()
+
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			var r io.Reader = rc
+			if limit >= 0 {
+				r = io.LimitReader(rc, int64(limit))
+			}
+			// Closing the copy drops its hold on the buffer, leaving the
+			// logging's
+			reqBuf, copied := d.captureBody(io.NopCloser(r), 2)
+			copied.Close()
+			rc.Close()
+			return req, reqBuf, nil
+		}
+	}
 
-	fmt.Println("======= HTTP REQUEST =======")
-	fmt.Printf("URL: %s %s\n", req.Method, req.URL)
+	if req.ContentLength > 0 && (limit < 0 || req.ContentLength <= int64(limit)) {
+		reqBuf, body := d.captureBody(req.Body, 2)
+		req = req.WithContext(req.Context())
+		req.Body = body
+		// The buffer can replay the body should the transport resend it
+		if reqBuf != nil {
+			req.GetBody = reqBuf.getBody
+		}
+		return req, reqBuf, nil
+	}
+
+	reqTee := d.newCaptureBuffer()
+	req = req.WithContext(req.Context())
+	req.Body = &teeBody{ReadCloser: req.Body, w: reqTee}
+	return req, nil, reqTee
+}
+
+// logRequest prints detailed information about the outgoing HTTP request.
+// A streamed body is logged as far as it has been sent.
+func (d *DebugTransport) logRequest(x *exchange) {
+	req, body := x.req, x.requestBody()
+
+	// A partial body can't be decoded or redacted, so it is left out, as
+	// in structured logs
+	size := x.requestSize()
+	partial := int64(len(body)) < size
+	if partial {
+		body = nil
+	}
+
+	w, done := d.requestEntry(x)
+	defer done()
+
+	fmt.Fprintln(w, "======= HTTP REQUEST =======")
+	fmt.Fprintf(w, "Exchange: #%d\n", x.seq)
+	fmt.Fprintf(w, "URL: %s %s\n", req.Method, x.redaction.redactURL(req.URL))
+	if d.insecure(req) {
+		fmt.Fprintln(w, insecureWarning)
+	}
+	if x.id != "" {
+		fmt.Fprintf(w, "Request ID: %s\n", x.id)
+	}
+	if attempt := attemptFrom(req); attempt > 1 {
+		fmt.Fprintf(w, "Attempt: %d\n", attempt)
+	}
+	if x.repeated > 0 {
+		fmt.Fprintf(w, "Repeated: %d times since last logged\n", x.repeated)
+	}
+	if hop, from := redirectHop(req); hop > 0 {
+		fmt.Fprintf(w, "Redirect: hop %d, %s from %s %s\n", hop, from.Status, from.Request.Method, x.redaction.redactURL(from.Request.URL))
+	}
+
+	for _, op := range x.graphQL {
+		fmt.Fprintf(w, "GraphQL: %s\n", op)
+	}
+	if d.SOAP {
+		if action := soapAction(req.Header, body); action != "" {
+			fmt.Fprintf(w, "SOAP Action: %s\n", action)
+		}
+	}
 
 	// Print headers
-	for k, v := range req.Header {
-		fmt.Printf("%s: %v\n", k, v)
+	writeHeaders(w, x.redaction.redactHeaders(req.Header))
+
+	// Print request body; GraphQL is shown as its query and variables
+	// rather than the JSON wrapping them
+	if x.reqSkipped {
+		fmt.Fprintf(w, "\nBody: %s\n", captureSkipped)
+	} else if partial {
+		fmt.Fprintf(w, "\nBody: [%d bytes, not shown: larger than MaxBodyLog]\n", size)
+	} else if len(x.graphQL) > 0 && len(body) > 0 {
+		fmt.Fprintln(w, "\nBody:")
+		fmt.Fprintln(w, d.graphQLBody(x.graphQL))
+	} else if len(body) > 0 {
+		fmt.Fprintln(w, "\nBody:")
+		fmt.Fprintln(w, d.textBody(x, nil, req.Header, body))
 	}
 
-	// Print request body
-	if len(body) > 0 {
-		fmt.Println("\nBody:")
-		fmt.Println(string(body))
+	// Print the equivalent curl command
+	if d.Curl {
+		fmt.Fprintln(w, "\nCurl:")
+		fmt.Fprintln(w, d.curlCommand(req, body))
 	}
-	fmt.Println("============================")
+	fmt.Fprintln(w, "============================")
 }
 
 // logResponse prints detailed information about the incoming HTTP response
-func (d *DebugTransport) logResponse(resp *http.Response, body []byte) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
+func (d *DebugTransport) logResponse(x *exchange, resp *http.Response, body []byte, duration time.Duration) {
+	w, done := d.entry(x)
+	defer done()
 
-	fmt.Println("======= HTTP RESPONSE =======")
-	fmt.Printf("Status: %s\n", resp.Status)
+	d.writeResponseHead(w, x, resp, duration)
 
-	// Print headers
-	for k, v := range resp.Header {
-		fmt.Printf("%s: %v\n", k, v)
+	// GraphQL reports failures in the body, often with a 200 status
+	if len(x.graphQL) > 0 {
+		if errs := graphQLErrors(resp.Header, body); len(errs) > 0 {
+			fmt.Fprintf(w, "\nGraphQL errors: %d\n", len(errs))
+			for _, e := range errs {
+				fmt.Fprintf(w, "  - %s\n", e)
+			}
+		}
+	}
+
+	if d.SOAP {
+		if fault := soapFault(resp.Header, body); fault != "" {
+			fmt.Fprintf(w, "\nSOAP Fault: %s\n", fault)
+		}
 	}
 
 	// Print response body
-	if len(body) > 0 {
-		fmt.Println("\nBody:")
-		fmt.Println(string(body))
+	if x.respSkipped {
+		fmt.Fprintf(w, "\nBody: %s\n", captureSkipped)
+	} else if len(body) > 0 {
+		fmt.Fprintln(w, "\nBody:")
+		fmt.Fprintln(w, d.textBody(x, resp, resp.Header, body))
+	}
+
+	// Print phase timings
+	if x.timings != nil {
+		fmt.Fprintf(w, "\nTiming: %s\n", x.timings)
+	}
+	fmt.Fprintln(w, "=============================")
+}
+
+// bodyLog returns the loggable form of a body with headers h: decompressed,
+// then a summary for multipart or binary content or the redacted text
+// truncated to MaxBodyLog bytes. resp is the response the body belongs
+// to, nil for the request's. pretty indents JSON and XML; color
+// highlights it for a terminal.
+func (d *DebugTransport) bodyLog(x *exchange, resp *http.Response, h http.Header, body []byte, pretty, color bool) string {
+	r := x.redaction
+	body = decodeBody(h, body)
+
+	contentType := h.Get("Content-Type")
+	if boundary := multipartBoundary(contentType); boundary != "" {
+		return multipartSummary(r, contentType, boundary, body)
+	}
+	if isBinary(contentType, body) {
+		decoded, ok := d.decodeBinary(x, resp, body)
+		if !ok {
+			return binarySummary(contentType, body, d.HexPreview)
+		}
+		body = decoded
+	}
+
+	body = r.redactBody(body)
+
+	kind := bodyKind(contentType, body)
+	if pretty {
+		body = prettyBody(kind, body)
+	}
+
+	var truncated int
+	if limit := d.bodyLimit(); limit >= 0 && len(body) > limit {
+		body, truncated = body[:limit], len(body)-limit
 	}
-	fmt.Println("=============================")
+	if color {
+		body = colorBody(kind, body)
+	}
+
+	if truncated > 0 {
+		return fmt.Sprintf("%s... (%d bytes truncated)", body, truncated)
+	}
+	return string(body)
+}
+
+// textBody renders a body for the text output, applying Pretty and,
+// when the exchange's output is a terminal, colors
+func (d *DebugTransport) textBody(x *exchange, resp *http.Response, h http.Header, body []byte) string {
+	return d.bodyLog(x, resp, h, body, d.Pretty, d.Pretty && !d.NoColor && isTerminal(x.w))
+}
+
+// sampled decides whether a request is logged under SampleRate
+func (d *DebugTransport) sampled() bool {
+	return d.SampleRate <= 0 || d.SampleRate >= 1 || rand.Float64() < d.SampleRate
 }
 
-// NewClient creates an HTTP client with debug logging
-func NewClient() *http.Client {
-	return &http.Client{
-		Transport: &DebugTransport{},
+// logError prints the failure of a request that got no response
+func (d *DebugTransport) logError(x *exchange, err error, duration time.Duration) {
+	w, done := d.entry(x)
+	defer done()
+
+	fmt.Fprintln(w, "======= HTTP ERROR =======")
+	fmt.Fprintf(w, "Exchange: #%d\n", x.seq)
+	fmt.Fprintf(w, "URL: %s %s\n", x.req.Method, x.redaction.redactURL(x.req.URL))
+	if x.id != "" {
+		fmt.Fprintf(w, "Request ID: %s\n", x.id)
+	}
+	fmt.Fprintf(w, "Duration: %s\n", d.durationText(duration))
+	if proxy := x.proxy.String(); proxy != "" {
+		fmt.Fprintf(w, "Proxy: %s\n", proxy)
+	}
+	if conn := x.conn.String(); conn != "" {
+		fmt.Fprintf(w, "Conn: %s\n", conn)
+	}
+	if phase := x.phase.phase(); phase != "" {
+		fmt.Fprintf(w, "Phase: %s\n", phase)
+	}
+	fmt.Fprintf(w, "Error: %v\n", err)
+	fmt.Fprintln(w, "==========================")
+}
+
+// writeResponseHead prints the start of a response block up to its
+// headers to an entry's writer
+func (d *DebugTransport) writeResponseHead(w io.Writer, x *exchange, resp *http.Response, duration time.Duration) {
+	fmt.Fprintln(w, "======= HTTP RESPONSE =======")
+	fmt.Fprintf(w, "Exchange: #%d\n", x.seq)
+	fmt.Fprintf(w, "Status: %s\n", resp.Status)
+	fmt.Fprintf(w, "Protocol: %s\n", protocolText(resp))
+	if x.id != "" {
+		fmt.Fprintf(w, "Request ID: %s\n", x.id)
+	}
+	fmt.Fprintf(w, "Duration: %s\n", d.durationText(duration))
+	if proxy := x.proxy.String(); proxy != "" {
+		fmt.Fprintf(w, "Proxy: %s\n", proxy)
+	}
+	if conn := x.conn.String(); conn != "" {
+		fmt.Fprintf(w, "Conn: %s\n", conn)
+	}
+	if d.TLSInfo && resp.TLS != nil {
+		d.writeTLS(w, resp.TLS)
+	}
+
+	// Print headers
+	writeHeaders(w, x.redaction.redactHeaders(resp.Header))
+}
+
+// isSlow reports whether duration exceeds SlowThreshold
+func (d *DebugTransport) isSlow(duration time.Duration) bool {
+	return d.SlowThreshold > 0 && duration > d.SlowThreshold
+}
+
+// durationText formats a response time, marking slow ones
+func (d *DebugTransport) durationText(duration time.Duration) string {
+	text := duration.Round(time.Microsecond).String()
+	if d.isSlow(duration) {
+		text += fmt.Sprintf(" [SLOW > %s]", d.SlowThreshold)
+	}
+	return text
+}
+
+// bodyLimit returns the body logging limit, negative for none
+func (d *DebugTransport) bodyLimit() int {
+	if d.MaxBodyLog == 0 {
+		return DefaultMaxBodyLog
+	}
+	return d.MaxBodyLog
+}
+
+// writeHeaders prints headers sorted by name
+func writeHeaders(w io.Writer, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s: %v\n", k, h[k])
+	}
+}
+
+// NewClient creates an HTTP client with debug logging, configured by opts.
+// Its Transport is the *DebugTransport unless WithRetry or WithHeaders
+// wrap it.
+//
+//	client := httpdbg.NewClient(
+//		httpdbg.WithTimeout(10*time.Second),
+//		httpdbg.WithRetry(3),
+//		httpdbg.WithHeaders(http.Header{"Authorization": {httpdbg.BearerAuth(token)}}),
+//	)
+func NewClient(opts ...Option) *http.Client {
+	c := &clientConfig{
+		client: &http.Client{},
+		debug:  &DebugTransport{},
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c.build()
 }
@@ -0,0 +1,503 @@
+// httpdbg/websocket.go
+package httpdbg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultWebSocketPreview is how many payload bytes of each frame are
+// logged when WebSocketDialer.Preview is zero
+const DefaultWebSocketPreview = 256
+
+// maxWebSocketHandshake caps the handshake bytes buffered for parsing; a
+// connection whose handshake is larger is not logged
+const maxWebSocketHandshake = 64 << 10
+
+// WebSocket opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// WebSocketDialer dials connections that log the WebSocket upgrade and
+// every frame sent and received over them (direction, opcode, size,
+// payload preview, close code). DebugTransport only sees the upgrade
+// request, if that; plug the dialer into the WebSocket library instead,
+// e.g. for gorilla/websocket:
+//
+//	wd := &httpdbg.WebSocketDialer{}
+//	dialer := websocket.Dialer{NetDialContext: wd.DialContext, NetDialTLSContext: wd.DialTLSContext}
+//
+// The log goes through Debug, honouring its Output (or WithOutput on the
+// dial context), Logger, Format and Redaction. As with streamed bodies,
+// JSON redaction only applies to payloads that fit in the preview.
+// Compressed frames (permessage-deflate) are logged without their payload.
+type WebSocketDialer struct {
+	// Dial opens the underlying connections; defaults to a net.Dialer
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig is used by DialTLSContext; ServerName defaults to the host
+	// dialled
+	TLSConfig *tls.Config
+	// Debug formats and writes the log; defaults to a DebugTransport with
+	// default settings
+	Debug *DebugTransport
+	// Preview is how many payload bytes of each frame are logged; defaults
+	// to DefaultWebSocketPreview
+	Preview int
+}
+
+// DialContext dials addr and returns a connection that logs its traffic
+func (wd *WebSocketDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := wd.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return wd.Wrap(ctx, conn, addr), nil
+}
+
+// DialTLSContext dials addr over TLS and returns a connection that logs
+// its decrypted traffic
+func (wd *WebSocketDialer) DialTLSContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := wd.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{}
+	if wd.TLSConfig != nil {
+		cfg = wd.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		cfg.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return wd.Wrap(ctx, tlsConn, addr), nil
+}
+
+// Wrap returns conn logging its traffic, which must start with the
+// WebSocket handshake. ctx selects the output, as with WithOutput.
+func (wd *WebSocketDialer) Wrap(ctx context.Context, conn net.Conn, addr string) net.Conn {
+	d := wd.Debug
+	if d == nil {
+		d = &DebugTransport{}
+	}
+	preview := wd.Preview
+	if preview <= 0 {
+		preview = DefaultWebSocketPreview
+	}
+
+	w := d.outputContext(ctx)
+	l := &wsLog{
+		d:    d,
+		ctx:  ctx,
+		addr: addr,
+		x:    &exchange{w: w, logger: d.logger(w), redaction: d.hostRedaction(addr)},
+	}
+	return &wsConn{
+		Conn:     conn,
+		sent:     &wsStream{l: l, sent: true, preview: preview},
+		received: &wsStream{l: l, preview: preview},
+	}
+}
+
+// dial opens the underlying connection
+func (wd *WebSocketDialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if wd.Dial != nil {
+		return wd.Dial(ctx, network, addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, addr)
+}
+
+// wsConn logs the bytes written and read as WebSocket traffic
+type wsConn struct {
+	net.Conn
+	sent, received *wsStream
+}
+
+func (c *wsConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.received.feed(p[:n])
+	return n, err
+}
+
+func (c *wsConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.sent.feed(p[:n])
+	return n, err
+}
+
+// wsStream parses one direction of a connection: the handshake, then
+// frames. Payloads are not buffered beyond the preview.
+type wsStream struct {
+	l       *wsLog
+	sent    bool
+	preview int
+
+	// off stops parsing, e.g. when the upgrade was refused
+	off       bool
+	upgraded  bool
+	handshake bytes.Buffer
+
+	header    []byte
+	frame     wsFrame
+	inPayload bool
+	remaining uint64
+}
+
+// wsFrame is a frame being parsed
+type wsFrame struct {
+	fin        bool
+	compressed bool
+	opcode     byte
+	length     uint64
+	masked     bool
+	mask       [4]byte
+	read       uint64
+	payload    []byte
+}
+
+// feed parses the next bytes of the stream
+func (s *wsStream) feed(p []byte) {
+	for len(p) > 0 && !s.off {
+		switch {
+		case !s.upgraded:
+			p = s.feedHandshake(p)
+		case !s.inPayload:
+			p = s.feedHeader(p)
+		default:
+			p = s.feedPayload(p)
+		}
+	}
+}
+
+// feedHandshake buffers the handshake until its blank line and logs it,
+// returning the bytes after it
+func (s *wsStream) feedHandshake(p []byte) []byte {
+	s.handshake.Write(p)
+	buf := s.handshake.Bytes()
+	end := bytes.Index(buf, []byte("\r\n\r\n"))
+	if end < 0 {
+		if len(buf) > maxWebSocketHandshake {
+			s.off = true
+		}
+		return nil
+	}
+
+	s.upgraded = true
+	if !s.l.handshake(s.sent, buf[:end+4]) {
+		s.off = true
+		return nil
+	}
+	rest := bytes.Clone(buf[end+4:])
+	s.handshake = bytes.Buffer{}
+	return rest
+}
+
+// feedHeader collects a frame header, returning the bytes after it
+func (s *wsStream) feedHeader(p []byte) []byte {
+	for len(p) > 0 {
+		s.header = append(s.header, p[0])
+		p = p[1:]
+		if n := wsHeaderLen(s.header); n > 0 && len(s.header) == n {
+			s.startFrame()
+			return p
+		}
+	}
+	return p
+}
+
+// wsHeaderLen returns the length of the frame header starting h, or 0
+// until enough of it is known
+func wsHeaderLen(h []byte) int {
+	if len(h) < 2 {
+		return 0
+	}
+	n := 2
+	switch h[1] & 0x7f {
+	case 126:
+		n += 2
+	case 127:
+		n += 8
+	}
+	if h[1]&0x80 != 0 {
+		n += 4
+	}
+	return n
+}
+
+// startFrame decodes the collected header
+func (s *wsStream) startFrame() {
+	h := s.header
+	f := wsFrame{
+		fin:        h[0]&0x80 != 0,
+		compressed: h[0]&0x40 != 0,
+		opcode:     h[0] & 0x0f,
+		masked:     h[1]&0x80 != 0,
+	}
+
+	i := 2
+	switch n := h[1] & 0x7f; n {
+	case 126:
+		f.length = uint64(binary.BigEndian.Uint16(h[2:4]))
+		i += 2
+	case 127:
+		f.length = binary.BigEndian.Uint64(h[2:10])
+		i += 8
+	default:
+		f.length = uint64(n)
+	}
+	if f.masked {
+		copy(f.mask[:], h[i:i+4])
+	}
+
+	s.header = s.header[:0]
+	s.frame = f
+	s.remaining = f.length
+	s.inPayload = f.length > 0
+	if !s.inPayload {
+		s.l.frame(s.sent, &s.frame)
+	}
+}
+
+// feedPayload consumes payload bytes, keeping the preview, and returns
+// the bytes after the frame
+func (s *wsStream) feedPayload(p []byte) []byte {
+	n := uint64(len(p))
+	if n > s.remaining {
+		n = s.remaining
+	}
+
+	f := &s.frame
+	for i := uint64(0); i < n && len(f.payload) < s.preview; i++ {
+		b := p[i]
+		if f.masked {
+			b ^= f.mask[(f.read+i)%4]
+		}
+		f.payload = append(f.payload, b)
+	}
+	f.read += n
+	s.remaining -= n
+
+	if s.remaining == 0 {
+		s.inPayload = false
+		s.l.frame(s.sent, f)
+	}
+	return p[n:]
+}
+
+// wsLog writes the log of one connection
+type wsLog struct {
+	d    *DebugTransport
+	ctx  context.Context
+	addr string
+	x    *exchange
+	// mu orders the entries of the two directions
+	mu sync.Mutex
+}
+
+// direction names the way a frame went
+func direction(sent bool) string {
+	if sent {
+		return "sent"
+	}
+	return "received"
+}
+
+// handshake logs the upgrade request or response, reporting whether the
+// connection was upgraded
+func (l *wsLog) handshake(sent bool, head []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := bufio.NewReader(bytes.NewReader(head))
+	if sent {
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return false
+		}
+		l.logUpgrade(req)
+		return true
+	}
+
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		return false
+	}
+	l.logUpgradeResponse(resp)
+	return resp.StatusCode == http.StatusSwitchingProtocols
+}
+
+// logUpgrade logs the upgrade request
+func (l *wsLog) logUpgrade(req *http.Request) {
+	x := l.x
+	if x.logger != nil {
+		x.logger.Log(l.ctx, LevelDebug, "websocket upgrade",
+			Field{"addr", l.addr},
+			Field{"method", req.Method},
+			Field{"url", x.redaction.redactURL(req.URL)},
+			Field{"request_headers", headerMap(x.redaction.redactHeaders(req.Header))},
+		)
+		return
+	}
+
+	w, done := l.d.entry(x)
+	defer done()
+	fmt.Fprintf(w, "======= WEBSOCKET UPGRADE %s =======\n", l.addr)
+	fmt.Fprintf(w, "URL: %s %s\n", req.Method, x.redaction.redactURL(req.URL))
+	writeHeaders(w, x.redaction.redactHeaders(req.Header))
+	fmt.Fprintln(w, "=====================================")
+}
+
+// logUpgradeResponse logs the server's answer to the upgrade
+func (l *wsLog) logUpgradeResponse(resp *http.Response) {
+	x := l.x
+	if x.logger != nil {
+		level := LevelDebug
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			level = LevelWarn
+		}
+		x.logger.Log(l.ctx, level, "websocket upgrade response",
+			Field{"addr", l.addr},
+			Field{"status", resp.StatusCode},
+			Field{"response_headers", headerMap(x.redaction.redactHeaders(resp.Header))},
+		)
+		return
+	}
+
+	w, done := l.d.entry(x)
+	defer done()
+	fmt.Fprintf(w, "======= WEBSOCKET RESPONSE %s =======\n", l.addr)
+	fmt.Fprintf(w, "Status: %s\n", resp.Status)
+	writeHeaders(w, x.redaction.redactHeaders(resp.Header))
+	fmt.Fprintln(w, "======================================")
+}
+
+// frame logs a complete frame
+func (l *wsLog) frame(sent bool, f *wsFrame) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	x := l.x
+	opcode := wsOpcodeName(f.opcode)
+	payload := l.payloadText(f)
+
+	var closeCode int
+	var closeReason string
+	if f.opcode == wsClose && len(f.payload) >= 2 {
+		closeCode = int(binary.BigEndian.Uint16(f.payload))
+		closeReason = string(f.payload[2:])
+		payload = ""
+	}
+
+	if x.logger != nil {
+		fields := []Field{
+			{"addr", l.addr},
+			{"direction", direction(sent)},
+			{"opcode", opcode},
+			{"length", f.length},
+			{"fin", f.fin},
+		}
+		if payload != "" {
+			fields = append(fields, Field{"payload", payload})
+		}
+		level := LevelDebug
+		if closeCode != 0 {
+			fields = append(fields, Field{"close_code", closeCode}, Field{"close_reason", closeReason})
+			if closeCode != 1000 && closeCode != 1001 {
+				level = LevelWarn
+			}
+		}
+		x.logger.Log(l.ctx, level, "websocket frame", fields...)
+		return
+	}
+
+	arrow := "<-"
+	if sent {
+		arrow = "->"
+	}
+	w, done := l.d.entry(x)
+	defer done()
+	fmt.Fprintf(w, "WebSocket %s %s %s (%d bytes", l.addr, arrow, opcode, f.length)
+	if !f.fin {
+		fmt.Fprint(w, ", more to follow")
+	}
+	fmt.Fprint(w, ")")
+	switch {
+	case closeCode != 0:
+		fmt.Fprintf(w, ": code %d %q", closeCode, closeReason)
+	case payload != "":
+		fmt.Fprintf(w, ": %s", payload)
+	}
+	fmt.Fprintln(w)
+}
+
+// payloadText renders a frame's payload preview: text as is (redacted
+// when it is complete JSON), binary in hex
+func (l *wsLog) payloadText(f *wsFrame) string {
+	if len(f.payload) == 0 {
+		return ""
+	}
+	if f.compressed {
+		return "[compressed]"
+	}
+
+	complete := uint64(len(f.payload)) == f.length
+	var text string
+	if f.opcode != wsBinary && utf8.Valid(f.payload) {
+		body := f.payload
+		if complete {
+			body = l.x.redaction.redactBody(body)
+		}
+		text = string(body)
+	} else {
+		text = hex.EncodeToString(f.payload)
+	}
+	if !complete {
+		text += fmt.Sprintf("... (%d bytes truncated)", f.length-uint64(len(f.payload)))
+	}
+	return text
+}
+
+// wsOpcodeName names a frame opcode
+func wsOpcodeName(op byte) string {
+	switch op {
+	case wsContinuation:
+		return "continuation"
+	case wsText:
+		return "text"
+	case wsBinary:
+		return "binary"
+	case wsClose:
+		return "close"
+	case wsPing:
+		return "ping"
+	case wsPong:
+		return "pong"
+	default:
+		return fmt.Sprintf("opcode %#x", op)
+	}
+}
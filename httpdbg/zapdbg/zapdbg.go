@@ -0,0 +1,64 @@
+// httpdbg/zapdbg/zapdbg.go
+package zapdbg
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"your/path/to/httpdbg"
+)
+
+// logger adapts a *zap.Logger to httpdbg.Logger
+type logger struct {
+	l *zap.Logger
+}
+
+// New returns an httpdbg.Logger writing to l:
+//
+//	transport := &httpdbg.DebugTransport{Logger: zapdbg.New(zapLogger)}
+func New(l *zap.Logger) httpdbg.Logger {
+	return logger{l: l}
+}
+
+func (z logger) Log(ctx context.Context, level httpdbg.Level, msg string, fields ...httpdbg.Field) {
+	ce := z.l.Check(zapLevel(level), msg)
+	if ce == nil {
+		return
+	}
+
+	zf := make([]zap.Field, 0, len(fields))
+	for _, f := range fields {
+		if m, ok := f.Value.(map[string]string); ok {
+			zf = append(zf, zap.Any(f.Key, stringMap(m)))
+			continue
+		}
+		zf = append(zf, zap.Any(f.Key, f.Value))
+	}
+	ce.Write(zf...)
+}
+
+// zapLevel maps an httpdbg level onto zap
+func zapLevel(l httpdbg.Level) zapcore.Level {
+	switch l {
+	case httpdbg.LevelDebug:
+		return zapcore.DebugLevel
+	case httpdbg.LevelInfo:
+		return zapcore.InfoLevel
+	case httpdbg.LevelWarn:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// stringMap encodes header maps as nested objects rather than reflection
+type stringMap map[string]string
+
+func (m stringMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range m {
+		enc.AddString(k, v)
+	}
+	return nil
+}
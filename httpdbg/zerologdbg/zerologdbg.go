@@ -0,0 +1,49 @@
+// httpdbg/zerologdbg/zerologdbg.go
+package zerologdbg
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+
+	"your/path/to/httpdbg"
+)
+
+// logger adapts a zerolog.Logger to httpdbg.Logger
+type logger struct {
+	l zerolog.Logger
+}
+
+// New returns an httpdbg.Logger writing to l:
+//
+//	transport := &httpdbg.DebugTransport{Logger: zerologdbg.New(log.Logger)}
+func New(l zerolog.Logger) httpdbg.Logger {
+	return logger{l: l}
+}
+
+func (z logger) Log(ctx context.Context, level httpdbg.Level, msg string, fields ...httpdbg.Field) {
+	event := z.l.WithLevel(zerologLevel(level))
+	if event == nil {
+		return
+	}
+
+	m := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value
+	}
+	event.Fields(m).Msg(msg)
+}
+
+// zerologLevel maps an httpdbg level onto zerolog
+func zerologLevel(l httpdbg.Level) zerolog.Level {
+	switch l {
+	case httpdbg.LevelDebug:
+		return zerolog.DebugLevel
+	case httpdbg.LevelInfo:
+		return zerolog.InfoLevel
+	case httpdbg.LevelWarn:
+		return zerolog.WarnLevel
+	default:
+		return zerolog.ErrorLevel
+	}
+}
@@ -0,0 +1,133 @@
+// db/idgen.go
+package db
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// IDGenerator produces client-side record IDs, so a record's ID is known
+// before it's inserted
+type IDGenerator interface {
+	NextID() (int64, error)
+}
+
+// AssignID sets record.ID from gen unless it already has one
+func AssignID(record *Record, gen IDGenerator) error {
+	if record.ID != 0 {
+		return nil
+	}
+	id, err := gen.NextID()
+	if err != nil {
+		return err
+	}
+	record.ID = id
+	return nil
+}
+
+// SnowflakeEpoch is the custom epoch (2024-01-01 UTC) snowflake IDs count from
+var SnowflakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	snowflakeNodeBits = 10
+	snowflakeSeqBits  = 12
+	snowflakeMaxNode  = 1<<snowflakeNodeBits - 1
+	snowflakeMaxSeq   = 1<<snowflakeSeqBits - 1
+)
+
+// Snowflake generates time-sortable 63-bit IDs made of a millisecond
+// timestamp, a node number and a per-millisecond sequence. Each process
+// writing to the same table needs a distinct node number.
+type Snowflake struct {
+	mu     sync.Mutex
+	node   int64
+	lastMs int64
+	seq    int64
+}
+
+// NewSnowflake creates a generator for the given node (0-1023)
+func NewSnowflake(node int64) (*Snowflake, error) {
+	if node < 0 || node > snowflakeMaxNode {
+		return nil, fmt.Errorf("snowflake node %d out of range 0-%d", node, snowflakeMaxNode)
+	}
+	return &Snowflake{node: node}, nil
+}
+
+// NextID returns the next snowflake ID, waiting for the next millisecond
+// if the sequence for the current one is exhausted
+func (s *Snowflake) NextID() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Since(SnowflakeEpoch).Milliseconds()
+	if now < s.lastMs {
+		return 0, fmt.Errorf("clock moved backwards by %dms", s.lastMs-now)
+	}
+
+	if now == s.lastMs {
+		s.seq = (s.seq + 1) & snowflakeMaxSeq
+		if s.seq == 0 {
+			for now <= s.lastMs {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(SnowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		s.seq = 0
+	}
+	s.lastMs = now
+
+	return now<<(snowflakeNodeBits+snowflakeSeqBits) | s.node<<snowflakeSeqBits | s.seq, nil
+}
+
+// ULID is a 128-bit lexicographically sortable identifier: a 48-bit
+// millisecond timestamp followed by 80 random bits. It doesn't fit the
+// int64 records.id, so it isn't an IDGenerator; use it for the UUID keyed
+// records_uuid table instead, setting UUIDRecord.ID to u.UUID() before
+// Repository.Insert.
+type ULID [16]byte
+
+// NewULID generates a ULID for the current time
+func NewULID() (ULID, error) {
+	var u ULID
+	ms := uint64(time.Now().UnixMilli())
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(u[:6], ts[2:])
+
+	if _, err := rand.Read(u[6:]); err != nil {
+		return u, err
+	}
+	return u, nil
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// String encodes the ULID as 26 Crockford base32 characters
+func (u ULID) String() string {
+	out := make([]byte, 26)
+	// 26 characters hold 130 bits; the top two are always zero
+	for i := 0; i < 26; i++ {
+		shift := 130 - 5*(i+1)
+		var v byte
+		for b := 4; b >= 0; b-- {
+			v <<= 1
+			bit := shift + b
+			if bit < 128 && u[15-bit/8]&(1<<(bit%8)) != 0 {
+				v |= 1
+			}
+		}
+		out[i] = crockford[v]
+	}
+	return string(out)
+}
+
+// UUID formats the ULID as a UUID string so it can be stored in
+// records_uuid while keeping its time ordering
+func (u ULID) UUID() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
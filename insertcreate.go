@@ -30,9 +30,8 @@ func TimeToUnix(t time.Time) int64 {
 	return t.Unix()
 }
 
-// CreateTable creates the records table with BIGINT for timestamps
-func CreateTable(db *sql.DB) error {
-	query := `
+// createTableQuery creates the records table
+var createTableQuery = RegisterQuery("records.create_table", `
 	CREATE TABLE IF NOT EXISTS records (
 		id BIGSERIAL PRIMARY KEY,
 		name VARCHAR(255) NOT NULL,
@@ -42,22 +41,55 @@ func CreateTable(db *sql.DB) error {
 		created_at BIGINT NOT NULL,    -- Unix timestamp
 		updated_at BIGINT              -- Nullable Unix timestamp
 	);
-	`
-	_, err := db.Exec(query)
+	`)
+
+// CreateTable creates the records table with BIGINT for timestamps
+func CreateTable(db *sql.DB) error {
+	_, err := createTableQuery.Exec(db)
 	return err
 }
 
-// InsertRecord inserts a single record, working with Unix timestamps
-func InsertRecord(db *sql.DB, record *Record) error {
-	query := `
+// insertRecordQuery inserts a record and returns its generated ID
+var insertRecordQuery = RegisterQuery("records.insert", `
 	INSERT INTO records (
 		name, description, amount, is_active, created_at, updated_at
 	) VALUES (
 		$1, $2, $3, $4, $5, $6
-	) RETURNING id`
+	) RETURNING id`)
+
+// InsertOption configures InsertRecord
+type InsertOption func(*insertConfig)
+
+type insertConfig struct {
+	gen IDGenerator
+}
+
+// WithIDGenerator makes InsertRecord assign the ID on the client side with
+// AssignID instead of taking it from the sequence. A record that already
+// has an ID keeps it, so IDs can be handed out before the insert.
+func WithIDGenerator(gen IDGenerator) InsertOption {
+	return func(c *insertConfig) {
+		c.gen = gen
+	}
+}
+
+// InsertRecord inserts a single record, working with Unix timestamps.
+// The database assigns the ID and any ID already on the record is
+// overwritten, unless WithIDGenerator is given.
+func InsertRecord(db *sql.DB, record *Record, opts ...InsertOption) error {
+	var cfg insertConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.gen != nil {
+		if err := AssignID(record, cfg.gen); err != nil {
+			return err
+		}
+		return insertRecordWithID(db, record)
+	}
 
-	err := db.QueryRow(
-		query,
+	err := insertRecordQuery.QueryRow(
+		db,
 		record.Name,
 		record.Description,
 		record.Amount,
@@ -69,14 +101,39 @@ func InsertRecord(db *sql.DB, record *Record) error {
 	return err
 }
 
+// insertRecordWithIDQuery inserts a record under a client-side ID
+var insertRecordWithIDQuery = RegisterQuery("records.insert_with_id", `
+	INSERT INTO records (
+		id, name, description, amount, is_active, created_at, updated_at
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, $7
+	)`)
+
+// insertRecordWithID inserts a record using its client-side ID
+func insertRecordWithID(db *sql.DB, record *Record) error {
+	_, err := insertRecordWithIDQuery.Exec(
+		db,
+		record.ID,
+		record.Name,
+		record.Description,
+		record.Amount,
+		record.IsActive,
+		record.CreatedAtUnix,
+		record.UpdatedAtUnix,
+	)
+	return err
+}
+
+// getRecordQuery selects a single record by ID
+var getRecordQuery = RegisterQuery("records.get", `
+	SELECT id, name, description, amount, is_active, created_at, updated_at
+	FROM records WHERE id = $1`)
+
 // GetRecord retrieves a record by ID
 func GetRecord(db *sql.DB, id int64) (*Record, error) {
 	record := &Record{}
-	query := `
-	SELECT id, name, description, amount, is_active, created_at, updated_at
-	FROM records WHERE id = $1`
 
-	err := db.QueryRow(query, id).Scan(
+	err := getRecordQuery.QueryRow(db, id).Scan(
 		&record.ID,
 		&record.Name,
 		&record.Description,
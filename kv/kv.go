@@ -0,0 +1,138 @@
+// db/kv/kv.go
+package kv
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrNilValue is returned when storing a nil value; store an empty slice
+// for an empty value
+var ErrNilValue = errors.New("kv: nil value")
+
+// CreateTable creates the kv_store table
+func CreateTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS kv_store (
+		key VARCHAR(255) PRIMARY KEY,
+		value BYTEA NOT NULL,
+		expires_at BIGINT,             -- Nullable Unix timestamp
+		updated_at BIGINT NOT NULL     -- Unix timestamp
+	);
+	CREATE INDEX IF NOT EXISTS kv_store_expires_at ON kv_store (expires_at)
+		WHERE expires_at IS NOT NULL;
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// expiry converts a TTL into a nullable Unix expiry timestamp, rounded
+// up to the next second so a sub-second TTL doesn't expire at once
+func expiry(now time.Time, ttl time.Duration) *int64 {
+	if ttl <= 0 {
+		return nil
+	}
+	t := now.Add(ttl)
+	at := t.Unix()
+	if t.Nanosecond() > 0 {
+		at++
+	}
+	return &at
+}
+
+// Get returns the value stored under key. ok is false when the key is
+// missing or expired.
+func Get(db *sql.DB, key string) (value []byte, ok bool, err error) {
+	query := `
+	SELECT value FROM kv_store
+	WHERE key = $1 AND (expires_at IS NULL OR expires_at > $2)`
+
+	err = db.QueryRow(query, key, time.Now().Unix()).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// Set stores value under key. A ttl of zero keeps the value until deleted.
+func Set(db *sql.DB, key string, value []byte, ttl time.Duration) error {
+	if value == nil {
+		return ErrNilValue
+	}
+	now := time.Now()
+	query := `
+	INSERT INTO kv_store (key, value, expires_at, updated_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (key) DO UPDATE SET
+		value = EXCLUDED.value,
+		expires_at = EXCLUDED.expires_at,
+		updated_at = EXCLUDED.updated_at`
+
+	_, err := db.Exec(query, key, value, expiry(now, ttl), now.Unix())
+	return err
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func Delete(db *sql.DB, key string) error {
+	_, err := db.Exec("DELETE FROM kv_store WHERE key = $1", key)
+	return err
+}
+
+// CompareAndSwap replaces the value under key with newValue only if it
+// currently equals oldValue. A nil oldValue means the key must not exist
+// (or be expired). It reports whether the swap happened.
+func CompareAndSwap(db *sql.DB, key string, oldValue, newValue []byte, ttl time.Duration) (bool, error) {
+	if newValue == nil {
+		return false, ErrNilValue
+	}
+	now := time.Now()
+
+	var result sql.Result
+	var err error
+	if oldValue == nil {
+		query := `
+		INSERT INTO kv_store (key, value, expires_at, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			value = EXCLUDED.value,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = EXCLUDED.updated_at
+		WHERE kv_store.expires_at IS NOT NULL AND kv_store.expires_at <= $4`
+
+		result, err = db.Exec(query, key, newValue, expiry(now, ttl), now.Unix())
+	} else {
+		query := `
+		UPDATE kv_store
+		SET value = $3, expires_at = $4, updated_at = $5
+		WHERE key = $1 AND value = $2
+			AND (expires_at IS NULL OR expires_at > $5)`
+
+		result, err = db.Exec(query, key, oldValue, newValue, expiry(now, ttl), now.Unix())
+	}
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// PurgeExpired deletes expired keys and returns how many were removed.
+// Expired keys are already invisible to Get; this just reclaims space.
+func PurgeExpired(db *sql.DB) (int64, error) {
+	result, err := db.Exec(
+		"DELETE FROM kv_store WHERE expires_at IS NOT NULL AND expires_at <= $1",
+		time.Now().Unix(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,107 @@
+// db/loader.go
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// LoadQueries reads every .sql file under dir in fsys (an embed.FS or
+// os.DirFS) and registers its statements. A file holding one statement is
+// registered under its file name without the extension; a file may also
+// hold several statements, each introduced by a comment line:
+//
+//	-- name: records.by_name
+//	SELECT id, name FROM records WHERE name = :name
+//
+// :name placeholders are rebound to $1..$n and their order is recorded in
+// NamedQuery.Params.
+func LoadQueries(fsys fs.FS, dir string) ([]NamedQuery, error) {
+	var loaded []NamedQuery
+
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path.Ext(p) != ".sql" {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		queries, err := parseQueryFile(strings.TrimSuffix(path.Base(p), ".sql"), string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %v", p, err)
+		}
+		loaded = append(loaded, queries...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range loaded {
+		registerNamedQuery(q)
+	}
+	return loaded, nil
+}
+
+// parseQueryFile splits a .sql file into named statements
+func parseQueryFile(defaultName, content string) ([]NamedQuery, error) {
+	type block struct {
+		name string
+		sql  strings.Builder
+	}
+
+	var blocks []*block
+	current := &block{name: defaultName}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			comment := strings.TrimSpace(strings.TrimPrefix(trimmed, "--"))
+			if strings.HasPrefix(comment, "name:") {
+				if strings.TrimSpace(current.sql.String()) != "" {
+					blocks = append(blocks, current)
+				}
+				current = &block{name: strings.TrimSpace(strings.TrimPrefix(comment, "name:"))}
+				continue
+			}
+		}
+		current.sql.WriteString(line)
+		current.sql.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(current.sql.String()) != "" {
+		blocks = append(blocks, current)
+	}
+
+	queries := make([]NamedQuery, 0, len(blocks))
+	seen := make(map[string]bool)
+	for _, b := range blocks {
+		if b.name == "" {
+			return nil, fmt.Errorf("statement without a name")
+		}
+		if seen[b.name] {
+			return nil, fmt.Errorf("duplicate statement name %q", b.name)
+		}
+		seen[b.name] = true
+
+		sql, params, err := rebindNamed(strings.TrimSpace(b.sql.String()))
+		if err != nil {
+			return nil, fmt.Errorf("statement %q: %v", b.name, err)
+		}
+		queries = append(queries, NamedQuery{Name: b.name, SQL: sql, Params: params})
+	}
+	return queries, nil
+}
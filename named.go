@@ -0,0 +1,221 @@
+// db/named.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Named rewrites a query with :name placeholders to positional form and
+// binds arg to it. arg is a map[string]interface{} or a struct (or pointer
+// to one) whose fields are matched by their db tag, falling back to the
+// lowercased field name:
+//
+//	query, args, err := db.Named(
+//		"UPDATE records SET name = :name WHERE id = :id", record)
+func Named(query string, arg interface{}) (string, []interface{}, error) {
+	rebound, params, err := rebindNamed(query)
+	if err != nil {
+		return "", nil, err
+	}
+	args, err := bindArgs(params, arg)
+	if err != nil {
+		return "", nil, err
+	}
+	return rebound, args, nil
+}
+
+// ExecNamed runs a statement with :name placeholders bound from arg
+func ExecNamed(db *sql.DB, query string, arg interface{}) (sql.Result, error) {
+	query, args, err := Named(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.Exec(query, args...)
+}
+
+// QueryNamed runs a query with :name placeholders bound from arg
+func QueryNamed(db *sql.DB, query string, arg interface{}) (*sql.Rows, error) {
+	query, args, err := Named(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return db.Query(query, args...)
+}
+
+// MustRegisterNamed registers a statement written with :name placeholders.
+// It panics if the statement can't be parsed, so it is meant for package
+// level declarations.
+func MustRegisterNamed(name, query string) NamedQuery {
+	rebound, params, err := rebindNamed(query)
+	if err != nil {
+		panic(fmt.Sprintf("db: query %q: %v", name, err))
+	}
+	q := NamedQuery{Name: name, SQL: rebound, Params: params}
+	registerNamedQuery(q)
+	return q
+}
+
+// BindArgs orders the values from arg to match the statement's Params
+func (q NamedQuery) BindArgs(arg interface{}) ([]interface{}, error) {
+	return bindArgs(q.Params, arg)
+}
+
+// bindArgs looks up each parameter name in a map or struct
+func bindArgs(params []string, arg interface{}) ([]interface{}, error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		args := make([]interface{}, len(params))
+		for i, name := range params {
+			v, ok := m[name]
+			if !ok {
+				return nil, fmt.Errorf("missing value for parameter :%s", name)
+			}
+			args[i] = v
+		}
+		return args, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("cannot bind parameters from a nil %T", arg)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot bind parameters from %T", arg)
+	}
+
+	fields := structFields(v.Type())
+	args := make([]interface{}, len(params))
+	for i, name := range params {
+		index, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%s has no field for parameter :%s", v.Type(), name)
+		}
+		field, err := v.FieldByIndexErr(index)
+		if err != nil {
+			return nil, fmt.Errorf("parameter :%s: %v", name, err)
+		}
+		args[i] = field.Interface()
+	}
+	return args, nil
+}
+
+// structFields maps parameter names to field indexes, including fields
+// promoted from embedded structs
+func structFields(t reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Tag.Get("db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Index
+	}
+	return fields
+}
+
+// rebindNamed rewrites :name placeholders to $1..$n and returns the
+// parameter names in positional order. A name used twice maps to the same
+// position. String literals, quoted identifiers, comments, dollar-quoted
+// bodies and :: casts are left alone.
+func rebindNamed(query string) (string, []string, error) {
+	var out strings.Builder
+	var params []string
+	positions := make(map[string]int)
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			out.WriteString(query[i : i+end+2])
+			i += end + 2
+
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			out.WriteString(query[i : i+end])
+			i += end
+
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			out.WriteString(query[i : i+end+4])
+			i += end + 4
+
+		case c == '$':
+			// Dollar-quoted body: $$...$$ or $tag$...$tag$
+			tagEnd := i + 1
+			for tagEnd < len(query) && isIdentChar(query[tagEnd]) {
+				tagEnd++
+			}
+			if tagEnd < len(query) && query[tagEnd] == '$' && (tagEnd == i+1 || !isDigit(query[i+1])) {
+				tag := query[i : tagEnd+1]
+				end := strings.Index(query[tagEnd+1:], tag)
+				if end < 0 {
+					return "", nil, fmt.Errorf("unterminated %s body at offset %d", tag, i)
+				}
+				stop := tagEnd + 1 + end + len(tag)
+				out.WriteString(query[i:stop])
+				i = stop
+				continue
+			}
+			out.WriteByte(c)
+			i++
+
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			out.WriteString("::")
+			i += 2
+
+		case c == ':' && i+1 < len(query) && isIdentStart(query[i+1]):
+			end := i + 1
+			for end < len(query) && isIdentChar(query[end]) {
+				end++
+			}
+			name := query[i+1 : end]
+			pos, ok := positions[name]
+			if !ok {
+				params = append(params, name)
+				pos = len(params)
+				positions[name] = pos
+			}
+			fmt.Fprintf(&out, "$%d", pos)
+			i = end
+
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String(), params, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,152 @@
+// db/partition.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreatePartitionedTable creates the records table partitioned by month on
+// created_at. Use it instead of CreateTable on high-volume installs; the
+// primary key has to include the partition column.
+func CreatePartitionedTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS records (
+		id BIGSERIAL,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		amount NUMERIC(15,2),
+		is_active BOOLEAN,
+		created_at BIGINT NOT NULL,    -- Unix timestamp
+		updated_at BIGINT,             -- Nullable Unix timestamp
+		PRIMARY KEY (id, created_at)
+	) PARTITION BY RANGE (created_at);
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// PartitionName returns the name of the partition holding the given month
+func PartitionName(month time.Time) string {
+	month = month.UTC()
+	return fmt.Sprintf("records_y%04dm%02d", month.Year(), int(month.Month()))
+}
+
+// parsePartitionName returns the month a partition created by
+// CreatePartition holds. ok is false for names that don't follow the
+// records_yYYYYmMM pattern, such as a default partition.
+func parsePartitionName(name string) (month time.Time, ok bool) {
+	month, err := time.Parse("records_y2006m01", name)
+	if err != nil || PartitionName(month) != name {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// isPartitioned reports whether the records table is partitioned
+func isPartitioned(db *sql.DB) (bool, error) {
+	var partitioned bool
+	err := db.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1
+			FROM pg_partitioned_table pt
+			JOIN pg_class c ON c.oid = pt.partrelid
+			WHERE c.relname = 'records'
+		)`).Scan(&partitioned)
+	return partitioned, err
+}
+
+// monthStart truncates t to the first instant of its month in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// CreatePartition creates the partition covering the month containing t
+func CreatePartition(db *sql.DB, t time.Time) error {
+	from := monthStart(t)
+	to := from.AddDate(0, 1, 0)
+
+	query := fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s PARTITION OF records FOR VALUES FROM (%d) TO (%d)",
+		PartitionName(from), TimeToUnix(from), TimeToUnix(to),
+	)
+	_, err := db.Exec(query)
+	return err
+}
+
+// EnsurePartitions creates partitions for the current month and the
+// given number of months ahead. Run it periodically, e.g. daily.
+func EnsurePartitions(db *sql.DB, now time.Time, monthsAhead int) error {
+	start := monthStart(now)
+	for i := 0; i <= monthsAhead; i++ {
+		if err := CreatePartition(db, start.AddDate(0, i, 0)); err != nil {
+			return fmt.Errorf("failed to create partition for %s: %v",
+				start.AddDate(0, i, 0).Format("2006-01"), err)
+		}
+	}
+	return nil
+}
+
+// ListPartitions returns the names of the records table partitions
+func ListPartitions(db *sql.DB) ([]string, error) {
+	query := `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = 'records'
+		ORDER BY c.relname
+	`
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// DetachPartition detaches the partition for the month containing t,
+// keeping its data as a standalone table
+func DetachPartition(db *sql.DB, t time.Time) error {
+	query := fmt.Sprintf("ALTER TABLE records DETACH PARTITION %s", PartitionName(t))
+	_, err := db.Exec(query)
+	return err
+}
+
+// DropPartitionsBefore drops every partition whose month ends before cutoff
+// and returns the names of the dropped partitions. Partitions not named by
+// PartitionName, such as a default partition, are never dropped.
+func DropPartitionsBefore(db *sql.DB, cutoff time.Time) ([]string, error) {
+	names, err := ListPartitions(db)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := monthStart(cutoff)
+	var dropped []string
+	for _, name := range names {
+		month, ok := parsePartitionName(name)
+		if !ok || !month.Before(limit) {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", name)); err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, name)
+	}
+
+	return dropped, nil
+}
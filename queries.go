@@ -0,0 +1,120 @@
+// db/queries.go
+package db
+
+import (
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NamedQuery is a SQL statement identified by a logical name, so latency
+// and errors can be reported per query rather than per raw SQL string
+type NamedQuery struct {
+	Name string
+	SQL  string
+	// Params lists the placeholder names for $1..$n when the statement
+	// was written with :name placeholders (see LoadQueries)
+	Params []string
+}
+
+// QueryObserver is called after every named query with its duration and
+// error. For QueryRow the duration covers executing the query, not Scan.
+type QueryObserver func(name string, duration time.Duration, err error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]NamedQuery)
+	observer   QueryObserver
+)
+
+// RegisterQuery adds a statement to the registry, replacing any previous
+// statement with the same name, and returns it
+func RegisterQuery(name, sql string) NamedQuery {
+	q := NamedQuery{Name: name, SQL: sql}
+	registerNamedQuery(q)
+	return q
+}
+
+// registerNamedQuery adds a fully built statement to the registry
+func registerNamedQuery(q NamedQuery) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[q.Name] = q
+}
+
+// LookupQuery returns the registered statement with the given name
+func LookupQuery(name string) (NamedQuery, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	q, ok := registry[name]
+	return q, ok
+}
+
+// RegisteredQueries returns all registered statements sorted by name
+func RegisteredQueries() []NamedQuery {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	queries := make([]NamedQuery, 0, len(registry))
+	for _, q := range registry {
+		queries = append(queries, q)
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	return queries
+}
+
+// SetQueryObserver installs fn as the observer for all named queries.
+// Pass nil to remove it.
+func SetQueryObserver(fn QueryObserver) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	observer = fn
+}
+
+// observe reports a finished query to the observer, if any
+func (q NamedQuery) observe(start time.Time, err error) {
+	registryMu.RLock()
+	fn := observer
+	registryMu.RUnlock()
+
+	if fn != nil {
+		fn(q.Name, time.Since(start), err)
+	}
+}
+
+// Exec runs the statement without returning rows
+func (q NamedQuery) Exec(db *sql.DB, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := db.Exec(q.SQL, args...)
+	q.observe(start, err)
+	return result, err
+}
+
+// ExecTx runs the statement inside tx without returning rows
+func (q NamedQuery) ExecTx(tx *sql.Tx, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := tx.Exec(q.SQL, args...)
+	q.observe(start, err)
+	return result, err
+}
+
+// Query runs the statement and returns its rows
+func (q NamedQuery) Query(db *sql.DB, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.Query(q.SQL, args...)
+	q.observe(start, err)
+	return rows, err
+}
+
+// QueryRow runs the statement expecting at most one row
+func (q NamedQuery) QueryRow(db *sql.DB, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := db.QueryRow(q.SQL, args...)
+	err := row.Err()
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	q.observe(start, err)
+	return row
+}
@@ -0,0 +1,160 @@
+// db/recorder.go
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Recorder is a fake database for tests. It records every statement run
+// through Recorder.DB and answers with stubbed results, so tests can check
+// exactly which queries ran without a real database:
+//
+//	rec := db.NewRecorder()
+//	rec.StubQuery("SELECT COUNT(*)", []string{"count"}, []interface{}{42})
+//	count, _ := db.GetRecordCount(rec.DB)
+//	require.NoError(t, rec.Verify("SELECT COUNT(*) FROM records"))
+//
+// Unstubbed statements report one affected row and return no rows.
+type Recorder struct {
+	*sql.DB
+
+	mu         sync.Mutex
+	statements []Statement
+	stubs      []recorderStub
+}
+
+// recorderStub is a canned answer for statements containing match
+type recorderStub struct {
+	match    string
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	r := &Recorder{}
+	r.DB = openCaptureDB(r)
+	return r
+}
+
+// StubQuery makes queries containing match return the given rows
+func (r *Recorder) StubQuery(match string, columns []string, rows ...[]interface{}) {
+	values := make([][]driver.Value, len(rows))
+	for i, row := range rows {
+		values[i] = make([]driver.Value, len(row))
+		for j, v := range row {
+			// database/sql only accepts int64 from drivers
+			if n, ok := v.(int); ok {
+				v = int64(n)
+			}
+			values[i][j] = v
+		}
+	}
+	r.addStub(recorderStub{match: match, columns: columns, rows: values})
+}
+
+// StubExec makes statements containing match report rowsAffected
+func (r *Recorder) StubExec(match string, rowsAffected int64) {
+	r.addStub(recorderStub{match: match, affected: rowsAffected})
+}
+
+// StubError makes statements containing match fail with err
+func (r *Recorder) StubError(match string, err error) {
+	r.addStub(recorderStub{match: match, err: err})
+}
+
+func (r *Recorder) addStub(s recorderStub) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stubs = append(r.stubs, s)
+}
+
+// Statements returns the recorded statements in execution order
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+// Queries returns the recorded query texts with whitespace collapsed
+func (r *Recorder) Queries() []string {
+	statements := r.Statements()
+	queries := make([]string, len(statements))
+	for i, stmt := range statements {
+		queries[i] = normalizeQuery(stmt.Query)
+	}
+	return queries
+}
+
+// Verify checks that exactly the given queries ran, in order. Queries are
+// compared with whitespace collapsed, so indentation doesn't matter.
+func (r *Recorder) Verify(queries ...string) error {
+	got := r.Queries()
+	for i := 0; i < len(got) || i < len(queries); i++ {
+		switch {
+		case i >= len(got):
+			return fmt.Errorf("query %d: expected %q, but no more queries ran", i, normalizeQuery(queries[i]))
+		case i >= len(queries):
+			return fmt.Errorf("query %d: unexpected %q", i, got[i])
+		case got[i] != normalizeQuery(queries[i]):
+			return fmt.Errorf("query %d: expected %q, got %q", i, normalizeQuery(queries[i]), got[i])
+		}
+	}
+	return nil
+}
+
+// Reset discards recorded statements and stubs
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = nil
+	r.stubs = nil
+}
+
+// record stores stmt and returns the first matching stub, if any
+func (r *Recorder) record(stmt Statement) *recorderStub {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, stmt)
+
+	query := normalizeQuery(stmt.Query)
+	for i := range r.stubs {
+		if strings.Contains(query, normalizeQuery(r.stubs[i].match)) {
+			return &r.stubs[i]
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) exec(stmt Statement) (driver.Result, error) {
+	s := r.record(stmt)
+	if s == nil {
+		return driver.RowsAffected(1), nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return driver.RowsAffected(s.affected), nil
+}
+
+func (r *Recorder) query(stmt Statement) (driver.Rows, error) {
+	s := r.record(stmt)
+	if s == nil {
+		return &cannedRows{}, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &cannedRows{columns: s.columns, values: s.rows}, nil
+}
+
+// normalizeQuery collapses runs of whitespace into single spaces
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
@@ -0,0 +1,159 @@
+// db/repository.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Key is the set of supported primary key types: int64 for BIGSERIAL
+// tables and string for UUID tables
+type Key interface {
+	int64 | string
+}
+
+// KeyedRecord mirrors Record with a configurable primary key type
+type KeyedRecord[K Key] struct {
+	ID          K        `db:"id"`
+	Name        string   `db:"name"`
+	Description *string  `db:"description"`
+	Amount      *float64 `db:"amount"`
+	IsActive    *bool    `db:"is_active"`
+	// Unix timestamps as int64
+	CreatedAtUnix int64  `db:"created_at"`
+	UpdatedAtUnix *int64 `db:"updated_at"` // Nullable
+}
+
+// Repository provides CRUD operations on a records-shaped table
+// regardless of its key type
+type Repository[K Key] struct {
+	db    *sql.DB
+	table string
+}
+
+// NewRepository creates a repository for the given table
+func NewRepository[K Key](db *sql.DB, table string) *Repository[K] {
+	return &Repository[K]{db: db, table: table}
+}
+
+// NewUUIDRepository creates a repository for the records_uuid table
+func NewUUIDRepository(db *sql.DB) *Repository[string] {
+	return NewRepository[string](db, "records_uuid")
+}
+
+// Insert inserts a record. A zero ID lets the database assign one,
+// otherwise the caller's ID is stored as-is.
+func (r *Repository[K]) Insert(record *KeyedRecord[K]) error {
+	var zero K
+	if record.ID == zero {
+		query := fmt.Sprintf(`
+		INSERT INTO %s (
+			name, description, amount, is_active, created_at, updated_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id`, r.table)
+
+		return r.db.QueryRow(
+			query,
+			record.Name,
+			record.Description,
+			record.Amount,
+			record.IsActive,
+			record.CreatedAtUnix,
+			record.UpdatedAtUnix,
+		).Scan(&record.ID)
+	}
+
+	query := fmt.Sprintf(`
+	INSERT INTO %s (
+		id, name, description, amount, is_active, created_at, updated_at
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, $7
+	)`, r.table)
+
+	_, err := r.db.Exec(
+		query,
+		record.ID,
+		record.Name,
+		record.Description,
+		record.Amount,
+		record.IsActive,
+		record.CreatedAtUnix,
+		record.UpdatedAtUnix,
+	)
+	return err
+}
+
+// Get retrieves a record by ID
+func (r *Repository[K]) Get(id K) (*KeyedRecord[K], error) {
+	record := &KeyedRecord[K]{}
+	query := fmt.Sprintf(`
+	SELECT id, name, description, amount, is_active, created_at, updated_at
+	FROM %s WHERE id = $1`, r.table)
+
+	err := r.db.QueryRow(query, id).Scan(
+		&record.ID,
+		&record.Name,
+		&record.Description,
+		&record.Amount,
+		&record.IsActive,
+		&record.CreatedAtUnix,
+		&record.UpdatedAtUnix,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// Update updates a record
+func (r *Repository[K]) Update(record *KeyedRecord[K]) error {
+	query := fmt.Sprintf(`
+	UPDATE %s
+	SET name = $1,
+		description = $2,
+		amount = $3,
+		is_active = $4,
+		updated_at = $5
+	WHERE id = $6`, r.table)
+
+	result, err := r.db.Exec(query,
+		record.Name,
+		record.Description,
+		record.Amount,
+		record.IsActive,
+		record.UpdatedAtUnix,
+		record.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, record.ID)
+}
+
+// Delete deletes a record by ID
+func (r *Repository[K]) Delete(id K) error {
+	result, err := r.db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = $1", r.table), id)
+	if err != nil {
+		return err
+	}
+
+	return requireAffected(result, id)
+}
+
+// requireAffected reports a not-found error when no row was changed
+func requireAffected(result sql.Result, id interface{}) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("record with ID %v not found", id)
+	}
+
+	return nil
+}
@@ -0,0 +1,243 @@
+// db/search/opensearch.go
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	db "your/path/to/db"
+)
+
+// OpenSearchIndexer indexes records through the OpenSearch/Elasticsearch
+// _bulk API
+type OpenSearchIndexer struct {
+	// URL of the cluster, e.g. http://localhost:9200
+	URL string
+	// IndexName defaults to "records"
+	IndexName string
+	// Client defaults to http.DefaultClient; use httpdbg.NewClient() to
+	// see the bulk requests
+	Client *http.Client
+	// Username and Password enable basic auth when set
+	Username string
+	Password string
+}
+
+// document is the indexed representation of a record
+type document struct {
+	ID          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Amount      *float64 `json:"amount,omitempty"`
+	IsActive    *bool    `json:"is_active,omitempty"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   *int64   `json:"updated_at,omitempty"`
+}
+
+// Index adds or replaces records in the index
+func (o *OpenSearchIndexer) Index(ctx context.Context, records []*db.Record) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, r := range records {
+		enc.Encode(map[string]interface{}{
+			"index": map[string]string{"_index": o.indexName(), "_id": strconv.FormatInt(r.ID, 10)},
+		})
+		enc.Encode(document{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			Amount:      r.Amount,
+			IsActive:    r.IsActive,
+			CreatedAt:   r.CreatedAtUnix,
+			UpdatedAt:   r.UpdatedAtUnix,
+		})
+	}
+	return o.bulk(ctx, &body)
+}
+
+// Delete removes records from the index; missing documents are ignored
+func (o *OpenSearchIndexer) Delete(ctx context.Context, ids []int64) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, id := range ids {
+		enc.Encode(map[string]interface{}{
+			"delete": map[string]string{"_index": o.indexName(), "_id": strconv.FormatInt(id, 10)},
+		})
+	}
+	return o.bulk(ctx, &body)
+}
+
+// scanPageSize is how many IDs ScanIDs reads per search request
+const scanPageSize = 1000
+
+// ScanIDs pages through the indexed record IDs in ascending order with
+// search_after, so Reindex can remove documents for deleted records
+func (o *OpenSearchIndexer) ScanIDs(ctx context.Context, fn func(ids []int64) error) error {
+	var after []interface{}
+	for {
+		query := map[string]interface{}{
+			"size":    scanPageSize,
+			"_source": false,
+			"sort":    []interface{}{map[string]string{"id": "asc"}},
+		}
+		if after != nil {
+			query["search_after"] = after
+		}
+
+		var result struct {
+			Hits struct {
+				Hits []struct {
+					ID   string        `json:"_id"`
+					Sort []interface{} `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if err := o.search(ctx, query, &result); err != nil {
+			return err
+		}
+
+		hits := result.Hits.Hits
+		if len(hits) == 0 {
+			return nil
+		}
+		ids := make([]int64, 0, len(hits))
+		for _, hit := range hits {
+			id, err := strconv.ParseInt(hit.ID, 10, 64)
+			if err != nil {
+				return fmt.Errorf("unexpected document id %q", hit.ID)
+			}
+			ids = append(ids, id)
+		}
+		if err := fn(ids); err != nil {
+			return err
+		}
+
+		if len(hits) < scanPageSize {
+			return nil
+		}
+		after = hits[len(hits)-1].Sort
+	}
+}
+
+// search runs a query against the index and decodes the response. An
+// index that doesn't exist yet has no hits.
+func (o *OpenSearchIndexer) search(ctx context.Context, query interface{}, result interface{}) error {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(o.URL, "/") + "/" + o.indexName() + "/_search"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := o.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("search request failed: %s: %s", resp.Status, data)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("decoding search response: %v", err)
+	}
+	return nil
+}
+
+// do sends a request with the configured client and credentials
+func (o *OpenSearchIndexer) do(req *http.Request) (*http.Response, error) {
+	if o.Username != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+func (o *OpenSearchIndexer) indexName() string {
+	if o.IndexName == "" {
+		return "records"
+	}
+	return o.IndexName
+}
+
+// bulkResponse is the part of the _bulk response we check
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk sends an NDJSON body to _bulk and reports per-item failures
+func (o *OpenSearchIndexer) bulk(ctx context.Context, body *bytes.Buffer) error {
+	if body.Len() == 0 {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(o.URL, "/")+"/_bulk", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := o.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bulk request failed: %s: %s", resp.Status, data)
+	}
+
+	var result bulkResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("decoding bulk response: %v", err)
+	}
+	if !result.Errors {
+		return nil
+	}
+
+	var failed []string
+	for _, item := range result.Items {
+		for action, r := range item {
+			// Deleting a document that isn't there is fine
+			if action == "delete" && r.Status == http.StatusNotFound {
+				continue
+			}
+			if r.Status >= 300 {
+				failed = append(failed, fmt.Sprintf("%s %s: %s", action, r.ID, r.Error))
+			}
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d bulk items failed: %s", len(failed), strings.Join(failed, "; "))
+}
@@ -0,0 +1,233 @@
+// db/search/search.go
+package search
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+
+	db "your/path/to/db"
+)
+
+// Indexer keeps an external search index of records
+type Indexer interface {
+	Index(ctx context.Context, records []*db.Record) error
+	Delete(ctx context.Context, ids []int64) error
+}
+
+// IDScanner is implemented by indexers that can list the record IDs they
+// hold. Reindex uses it to remove documents for deleted records.
+type IDScanner interface {
+	// ScanIDs calls fn with successive pages of indexed IDs
+	ScanIDs(ctx context.Context, fn func(ids []int64) error) error
+}
+
+// recordsAfterQuery pages through records by ID, so rows inserted or
+// deleted during a reindex don't shift the pages
+var recordsAfterQuery = db.RegisterQuery("search.records_after", `
+	SELECT id, name, description, amount, is_active, created_at, updated_at
+	FROM records
+	WHERE id > $1
+	ORDER BY id
+	LIMIT $2`)
+
+// existingIDsQuery returns which of the given IDs are still in records
+var existingIDsQuery = db.RegisterQuery("search.existing_ids",
+	"SELECT id FROM records WHERE id = ANY($1)")
+
+// Reindex pushes every record to the indexer in ID order, then removes
+// documents whose record is gone if the indexer is an IDScanner. Use it to
+// build a new index or repair one after the change feed was interrupted.
+func Reindex(ctx context.Context, database *sql.DB, indexer Indexer, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	total := 0
+	for lastID := int64(0); ; {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+
+		records, err := recordsAfter(database, lastID, batchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(records) == 0 {
+			break
+		}
+
+		if err := indexer.Index(ctx, records); err != nil {
+			return total, fmt.Errorf("indexing batch after id %d: %v", lastID, err)
+		}
+		total += len(records)
+		lastID = records[len(records)-1].ID
+
+		if len(records) < batchSize {
+			break
+		}
+	}
+
+	if scanner, ok := indexer.(IDScanner); ok {
+		if err := prune(ctx, database, indexer, scanner); err != nil {
+			return total, fmt.Errorf("removing deleted records: %v", err)
+		}
+	}
+	return total, nil
+}
+
+// recordsAfter returns up to limit records with IDs above lastID
+func recordsAfter(database *sql.DB, lastID int64, limit int) ([]*db.Record, error) {
+	rows, err := recordsAfterQuery.Query(database, lastID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*db.Record
+	for rows.Next() {
+		record := &db.Record{}
+		err := rows.Scan(
+			&record.ID,
+			&record.Name,
+			&record.Description,
+			&record.Amount,
+			&record.IsActive,
+			&record.CreatedAtUnix,
+			&record.UpdatedAtUnix,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// prune deletes indexed documents whose record no longer exists. Each page
+// is checked against the table when it's read, so records inserted since
+// the reindex started are kept.
+func prune(ctx context.Context, database *sql.DB, indexer Indexer, scanner IDScanner) error {
+	return scanner.ScanIDs(ctx, func(ids []int64) error {
+		rows, err := existingIDsQuery.Query(database, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		existing := make(map[int64]bool, len(ids))
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			existing[id] = true
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		var gone []int64
+		for _, id := range ids {
+			if !existing[id] {
+				gone = append(gone, id)
+			}
+		}
+		if len(gone) == 0 {
+			return nil
+		}
+		log.Printf("search: removing %d documents for deleted records", len(gone))
+		return indexer.Delete(ctx, gone)
+	})
+}
+
+// change is the payload sent by db.InstallNotifyTrigger
+type change struct {
+	Op string `json:"op"`
+	ID int64  `json:"id"`
+}
+
+// Syncer keeps an index in step with the records table by consuming the
+// NOTIFY change feed. Install the feed first with db.InstallNotifyTrigger.
+type Syncer struct {
+	DB      *sql.DB
+	Indexer Indexer
+	// ConnStr opens the dedicated LISTEN connection
+	ConnStr string
+	// Channel defaults to db.ChangeChannel
+	Channel string
+	// BatchSize is passed to Reindex; zero uses its default
+	BatchSize int
+}
+
+// Run applies changes to the index until ctx is cancelled. Changes made
+// while no listener was connected are never notified, so Run starts with
+// a full Reindex once it is listening, and runs another after every
+// reconnect. Notifications queued during a reindex are applied after it.
+func (s *Syncer) Run(ctx context.Context) error {
+	channel := s.Channel
+	if channel == "" {
+		channel = db.ChangeChannel
+	}
+
+	listener := pq.NewListener(s.ConnStr, time.Second, time.Minute, nil)
+	defer listener.Close()
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	// Listening first means anything committed during the reindex is
+	// still delivered afterwards
+	n, err := Reindex(ctx, s.DB, s.Indexer, s.BatchSize)
+	if err != nil {
+		return fmt.Errorf("reindex failed after %d records: %v", n, err)
+	}
+	log.Printf("search: reindexed %d records", n)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case n := <-listener.Notify:
+			if n == nil {
+				log.Printf("search: listener reconnected, reindexing to catch up")
+				if _, err := Reindex(ctx, s.DB, s.Indexer, s.BatchSize); err != nil {
+					log.Printf("search: catch-up reindex failed: %v", err)
+				}
+				continue
+			}
+
+			var c change
+			if err := json.Unmarshal([]byte(n.Extra), &c); err != nil {
+				log.Printf("search: ignoring malformed notification %q: %v", n.Extra, err)
+				continue
+			}
+			if err := s.apply(ctx, c); err != nil {
+				log.Printf("search: applying %s of record %d: %v", c.Op, c.ID, err)
+			}
+		}
+	}
+}
+
+// apply mirrors a single change into the index
+func (s *Syncer) apply(ctx context.Context, c change) error {
+	if c.Op == "DELETE" {
+		return s.Indexer.Delete(ctx, []int64{c.ID})
+	}
+
+	record, err := db.GetRecord(s.DB, c.ID)
+	if err == sql.ErrNoRows {
+		// Deleted again before we got to it
+		return s.Indexer.Delete(ctx, []int64{c.ID})
+	}
+	if err != nil {
+		return err
+	}
+	return s.Indexer.Index(ctx, []*db.Record{record})
+}
@@ -0,0 +1,46 @@
+// db/sequence.go
+package db
+
+import (
+	"database/sql"
+)
+
+// GetNextID reserves and returns the next value of records_id_seq.
+// Reserved IDs are never handed out again, even if unused.
+func GetNextID(db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRow("SELECT nextval('records_id_seq')").Scan(&id)
+	return id, err
+}
+
+// PeekSequence returns the ID the next insert will receive without
+// consuming it
+func PeekSequence(db *sql.DB) (int64, error) {
+	var lastValue int64
+	var isCalled bool
+	err := db.QueryRow("SELECT last_value, is_called FROM records_id_seq").Scan(&lastValue, &isCalled)
+	if err != nil {
+		return 0, err
+	}
+
+	if isCalled {
+		return lastValue + 1, nil
+	}
+	return lastValue, nil
+}
+
+// ResetSequence moves records_id_seq to just past the highest existing ID,
+// or back to 1 when the table is empty. Call it after importing records
+// with explicit IDs.
+func ResetSequence(db *sql.DB) error {
+	_, err := db.Exec(`
+		SELECT setval('records_id_seq', COALESCE(MAX(id), 1), MAX(id) IS NOT NULL)
+		FROM records`)
+	return err
+}
+
+// SetSequence makes next the ID handed out by the following insert
+func SetSequence(db *sql.DB, next int64) error {
+	_, err := db.Exec("SELECT setval('records_id_seq', $1, false)", next)
+	return err
+}
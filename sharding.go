@@ -0,0 +1,282 @@
+// db/sharding.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ShardedStore routes record operations across several databases by
+// hashing the record ID. Every shard must have the records table.
+type ShardedStore struct {
+	shards []*sql.DB
+}
+
+// NewShardedStore creates a store over the given shard databases.
+// The order of shards must stay the same between runs.
+func NewShardedStore(shards ...*sql.DB) (*ShardedStore, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("sharded store needs at least one shard")
+	}
+	return &ShardedStore{shards: shards}, nil
+}
+
+// ShardFor returns the shard responsible for an arbitrary shard key
+func (s *ShardedStore) ShardFor(key string) *sql.DB {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// shardForID returns the shard responsible for a record ID
+func (s *ShardedStore) shardForID(id int64) *sql.DB {
+	return s.ShardFor(strconv.FormatInt(id, 10))
+}
+
+// InsertRecord inserts a record on its shard. The ID must be assigned by
+// the caller (see AssignID), since a shard-local sequence can't produce
+// globally unique IDs.
+func (s *ShardedStore) InsertRecord(record *Record) error {
+	if record.ID == 0 {
+		return fmt.Errorf("sharded insert requires a pre-assigned record ID")
+	}
+
+	return insertRecordWithID(s.shardForID(record.ID), record)
+}
+
+// GetRecord retrieves a record by ID from its shard
+func (s *ShardedStore) GetRecord(id int64) (*Record, error) {
+	return GetRecord(s.shardForID(id), id)
+}
+
+// UpdateRecord updates a record on its shard
+func (s *ShardedStore) UpdateRecord(record *Record) error {
+	return UpdateRecord(s.shardForID(record.ID), record)
+}
+
+// DeleteRecord deletes a record by ID from its shard
+func (s *ShardedStore) DeleteRecord(id int64) error {
+	return DeleteRecord(s.shardForID(id), id)
+}
+
+// GetRecords queries every shard and merges the results into a single page.
+// NULLs sort last and names compare byte by byte, in both directions.
+func (s *ShardedStore) GetRecords(opts QueryOptions) ([]*Record, error) {
+	return s.queryRecords("", normalizeQueryOptions(opts))
+}
+
+// SearchRecords searches every shard and merges the results by created_at
+func (s *ShardedStore) SearchRecords(searchTerm string, opts QueryOptions) ([]*Record, error) {
+	opts = normalizeQueryOptions(opts)
+	// Like the single database SearchRecords, always newest first
+	opts.SortBy, opts.Order = "created_at", "DESC"
+	return s.queryRecords("%"+searchTerm+"%", opts)
+}
+
+// queryRecords fetches the first Offset+Limit rows from every shard and
+// pages through their merge. Each shard orders its rows exactly the way
+// sortRecords does, so the merged page matches a single table's.
+func (s *ShardedStore) queryRecords(pattern string, opts QueryOptions) ([]*Record, error) {
+	query, err := shardQuery(pattern != "", opts)
+	if err != nil {
+		return nil, err
+	}
+	args := []interface{}{opts.Limit + opts.Offset}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+
+	merged, err := s.fanOut(func(db *sql.DB) ([]*Record, error) {
+		rows, err := query.Query(db, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var records []*Record
+		for rows.Next() {
+			record := &Record{}
+			err := rows.Scan(
+				&record.ID,
+				&record.Name,
+				&record.Description,
+				&record.Amount,
+				&record.IsActive,
+				&record.CreatedAtUnix,
+				&record.UpdatedAtUnix,
+			)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+		}
+		return records, rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRecords(merged, opts.SortBy, opts.Order)
+	return pageRecords(merged, opts), nil
+}
+
+// shardQuery builds the per-shard query for opts. Only columns
+// compareRecords knows can be sorted on, since the merge has to agree
+// with the database on the order.
+func shardQuery(search bool, opts QueryOptions) (NamedQuery, error) {
+	switch opts.SortBy {
+	case "id", "name", "amount", "created_at", "updated_at":
+	default:
+		return NamedQuery{}, fmt.Errorf("cannot sort sharded records by %q", opts.SortBy)
+	}
+	order := strings.ToUpper(opts.Order)
+	if order != "ASC" && order != "DESC" {
+		return NamedQuery{}, fmt.Errorf("invalid sort order %q", opts.Order)
+	}
+
+	column := opts.SortBy
+	if column == "name" {
+		column += ` COLLATE "C"`
+	}
+	where := ""
+	if search {
+		where = "WHERE name ILIKE $2 OR description ILIKE $2"
+	}
+
+	// The order is dynamic, so this query is named but not registered
+	return NamedQuery{Name: "records.list_shard", SQL: fmt.Sprintf(`
+		SELECT id, name, description, amount, is_active, created_at, updated_at
+		FROM records
+		%s
+		ORDER BY %s %s NULLS LAST, id %s
+		LIMIT $1
+	`, where, column, order, order)}, nil
+}
+
+// fanOut runs fn against all shards concurrently and concatenates the results
+func (s *ShardedStore) fanOut(fn func(db *sql.DB) ([]*Record, error)) ([]*Record, error) {
+	results := make([][]*Record, len(s.shards))
+	errs := make([]error, len(s.shards))
+
+	var wg sync.WaitGroup
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func(i int, shard *sql.DB) {
+			defer wg.Done()
+			results[i], errs[i] = fn(shard)
+		}(i, shard)
+	}
+	wg.Wait()
+
+	var merged []*Record
+	for i := range s.shards {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("shard %d: %v", i, errs[i])
+		}
+		merged = append(merged, results[i]...)
+	}
+	return merged, nil
+}
+
+// normalizeQueryOptions applies the same defaults as GetRecords
+func normalizeQueryOptions(opts QueryOptions) QueryOptions {
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+	if opts.SortBy == "" {
+		opts.SortBy = "created_at"
+	}
+	if opts.Order == "" {
+		opts.Order = "DESC"
+	}
+	return opts
+}
+
+// pageRecords applies offset and limit to an already sorted slice
+func pageRecords(records []*Record, opts QueryOptions) []*Record {
+	if opts.Offset >= len(records) {
+		return nil
+	}
+	records = records[opts.Offset:]
+	if len(records) > opts.Limit {
+		records = records[:opts.Limit]
+	}
+	return records
+}
+
+// sortRecords sorts records in memory the way shardQuery orders them: by
+// one of the records table columns with NULLs last, then by id
+func sortRecords(records []*Record, sortBy, order string) {
+	desc := strings.EqualFold(order, "DESC")
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		an, bn := isNullColumn(a, sortBy), isNullColumn(b, sortBy)
+		if an != bn {
+			return bn
+		}
+		c := 0
+		if !an {
+			c = compareRecords(a, b, sortBy)
+		}
+		if c == 0 {
+			c = compareInt64(a.ID, b.ID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+}
+
+// isNullColumn reports whether the record's value for a column is NULL
+func isNullColumn(r *Record, column string) bool {
+	switch column {
+	case "amount":
+		return r.Amount == nil
+	case "updated_at":
+		return r.UpdatedAtUnix == nil
+	}
+	return false
+}
+
+// compareRecords compares two records on a non-NULL column. Names are
+// compared byte by byte, as COLLATE "C" does.
+func compareRecords(a, b *Record, column string) int {
+	switch column {
+	case "id":
+		return compareInt64(a.ID, b.ID)
+	case "name":
+		return strings.Compare(a.Name, b.Name)
+	case "amount":
+		return compareFloat(*a.Amount, *b.Amount)
+	case "updated_at":
+		return compareInt64(*a.UpdatedAtUnix, *b.UpdatedAtUnix)
+	default:
+		return compareInt64(a.CreatedAtUnix, b.CreatedAtUnix)
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
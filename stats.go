@@ -0,0 +1,87 @@
+// db/stats.go
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// TableStatistics describes the size and maintenance state of the
+// records table
+type TableStatistics struct {
+	RowEstimate int64 // planner estimate, cheap but approximate
+	TableBytes  int64 // heap plus TOAST
+	IndexBytes  int64
+	TotalBytes  int64
+	LiveTuples  int64
+	DeadTuples  int64
+	// BloatRatio estimates the share of dead tuples, 0 to 1. A high value
+	// means VACUUM is falling behind.
+	BloatRatio      float64
+	LastVacuum      *time.Time
+	LastAutovacuum  *time.Time
+	LastAnalyze     *time.Time
+	LastAutoanalyze *time.Time
+}
+
+// TableStats returns size and vacuum/analyze statistics for the records
+// table from the PostgreSQL catalogs
+func TableStats(db *sql.DB) (*TableStatistics, error) {
+	query := `
+		SELECT
+			c.reltuples::BIGINT,
+			pg_table_size(c.oid),
+			pg_indexes_size(c.oid),
+			pg_total_relation_size(c.oid),
+			COALESCE(s.n_live_tup, 0),
+			COALESCE(s.n_dead_tup, 0),
+			s.last_vacuum,
+			s.last_autovacuum,
+			s.last_analyze,
+			s.last_autoanalyze
+		FROM pg_class c
+		LEFT JOIN pg_stat_user_tables s ON s.relid = c.oid
+		WHERE c.oid = 'records'::regclass
+	`
+
+	stats := &TableStatistics{}
+	var lastVacuum, lastAutovacuum, lastAnalyze, lastAutoanalyze sql.NullTime
+	err := db.QueryRow(query).Scan(
+		&stats.RowEstimate,
+		&stats.TableBytes,
+		&stats.IndexBytes,
+		&stats.TotalBytes,
+		&stats.LiveTuples,
+		&stats.DeadTuples,
+		&lastVacuum,
+		&lastAutovacuum,
+		&lastAnalyze,
+		&lastAutoanalyze,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// reltuples is -1 for tables that have never been analyzed
+	if stats.RowEstimate < 0 {
+		stats.RowEstimate = stats.LiveTuples
+	}
+	if total := stats.LiveTuples + stats.DeadTuples; total > 0 {
+		stats.BloatRatio = float64(stats.DeadTuples) / float64(total)
+	}
+
+	stats.LastVacuum = nullTimePtr(lastVacuum)
+	stats.LastAutovacuum = nullTimePtr(lastAutovacuum)
+	stats.LastAnalyze = nullTimePtr(lastAnalyze)
+	stats.LastAutoanalyze = nullTimePtr(lastAutoanalyze)
+
+	return stats, nil
+}
+
+// nullTimePtr converts a nullable timestamp into a pointer
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,166 @@
+// db/sync.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SyncOptions controls how records are copied between databases
+type SyncOptions struct {
+	BatchSize int
+	// Since is the Unix timestamp watermark; only records created or updated
+	// at or after it are copied. Zero copies the whole table.
+	Since int64
+}
+
+// SyncResult reports what a Sync run copied
+type SyncResult struct {
+	Copied int64
+	// Watermark is the latest change timestamp seen; pass it as Since
+	// on the next run to continue incrementally.
+	Watermark int64
+}
+
+// Sync copies records from src to dst in batches, upserting by ID, or by
+// ID and created_at when dst was made with CreatePartitionedTable.
+// Records are streamed in (updated_at, id) order so an interrupted run
+// can be resumed from the returned watermark.
+func Sync(src, dst *sql.DB, opts SyncOptions) (*SyncResult, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	// A partitioned table's key includes created_at, so the upsert has
+	// to target both columns
+	conflict := "id"
+	partitioned, err := isPartitioned(dst)
+	if err != nil {
+		return nil, fmt.Errorf("sync failed to inspect destination: %v", err)
+	}
+	if partitioned {
+		conflict = "id, created_at"
+	}
+
+	result := &SyncResult{Watermark: opts.Since}
+	lastChanged, lastID := opts.Since, int64(0)
+
+	for {
+		batch, changed, err := fetchSyncBatch(src, lastChanged, lastID, opts.BatchSize)
+		if err != nil {
+			return result, fmt.Errorf("sync read failed: %v", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		if err := upsertSyncBatch(dst, batch, conflict); err != nil {
+			return result, fmt.Errorf("sync write failed: %v", err)
+		}
+
+		result.Copied += int64(len(batch))
+		lastChanged, lastID = changed[len(changed)-1], batch[len(batch)-1].ID
+		result.Watermark = lastChanged
+
+		if len(batch) < opts.BatchSize {
+			break
+		}
+	}
+
+	// Explicit IDs don't advance the sequence, so move it past the copied rows
+	if result.Copied > 0 {
+		if err := ResetSequence(dst); err != nil {
+			return result, fmt.Errorf("sync sequence update failed: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// fetchSyncBatch reads the next batch of records after the given position
+func fetchSyncBatch(db *sql.DB, lastChanged, lastID int64, limit int) ([]*Record, []int64, error) {
+	query := `
+		SELECT id, name, description, amount, is_active, created_at, updated_at,
+			COALESCE(updated_at, created_at) AS changed_at
+		FROM records
+		WHERE COALESCE(updated_at, created_at) > $1
+			OR (COALESCE(updated_at, created_at) = $1 AND id > $2)
+		ORDER BY changed_at, id
+		LIMIT $3
+	`
+
+	rows, err := db.Query(query, lastChanged, lastID, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var records []*Record
+	var changed []int64
+	for rows.Next() {
+		record := &Record{}
+		var changedAt int64
+		err := rows.Scan(
+			&record.ID,
+			&record.Name,
+			&record.Description,
+			&record.Amount,
+			&record.IsActive,
+			&record.CreatedAtUnix,
+			&record.UpdatedAtUnix,
+			&changedAt,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+		changed = append(changed, changedAt)
+	}
+
+	return records, changed, rows.Err()
+}
+
+// upsertSyncBatch writes a batch of records to dst in a single transaction,
+// resolving conflicts on the given key columns
+func upsertSyncBatch(db *sql.DB, records []*Record, conflict string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(fmt.Sprintf(`
+	INSERT INTO records (
+		id, name, description, amount, is_active, created_at, updated_at
+	) VALUES (
+		$1, $2, $3, $4, $5, $6, $7
+	)
+	ON CONFLICT (%s) DO UPDATE SET
+		name = EXCLUDED.name,
+		description = EXCLUDED.description,
+		amount = EXCLUDED.amount,
+		is_active = EXCLUDED.is_active,
+		created_at = EXCLUDED.created_at,
+		updated_at = EXCLUDED.updated_at`, conflict))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range records {
+		_, err := stmt.Exec(
+			record.ID,
+			record.Name,
+			record.Description,
+			record.Amount,
+			record.IsActive,
+			record.CreatedAtUnix,
+			record.UpdatedAtUnix,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
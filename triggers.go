@@ -0,0 +1,171 @@
+// db/triggers.go
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ChangeChannel is the default NOTIFY channel for record changes
+const ChangeChannel = "records_changes"
+
+// TriggerOptions selects which triggers InstallTriggers sets up
+type TriggerOptions struct {
+	// UpdatedAt sets updated_at to the current Unix time on every UPDATE
+	UpdatedAt bool
+	// Audit copies every change into the records_audit table
+	Audit bool
+	// Notify sends a NOTIFY with the operation and record ID on every change
+	Notify bool
+	// NotifyChannel overrides ChangeChannel
+	NotifyChannel string
+}
+
+// CreateTableWithTriggers creates the records table and installs the
+// selected triggers in one step
+func CreateTableWithTriggers(db *sql.DB, opts TriggerOptions) error {
+	if err := CreateTable(db); err != nil {
+		return err
+	}
+	return InstallTriggers(db, opts)
+}
+
+// InstallTriggers installs the selected triggers on the records table.
+// It is safe to run repeatedly.
+func InstallTriggers(db *sql.DB, opts TriggerOptions) error {
+	if opts.UpdatedAt {
+		if err := InstallUpdatedAtTrigger(db); err != nil {
+			return fmt.Errorf("failed to install updated_at trigger: %v", err)
+		}
+	}
+	if opts.Audit {
+		if err := InstallAuditTrigger(db); err != nil {
+			return fmt.Errorf("failed to install audit trigger: %v", err)
+		}
+	}
+	if opts.Notify {
+		if err := InstallNotifyTrigger(db, opts.NotifyChannel); err != nil {
+			return fmt.Errorf("failed to install notify trigger: %v", err)
+		}
+	}
+	return nil
+}
+
+// InstallUpdatedAtTrigger keeps updated_at current on every UPDATE,
+// overriding whatever value the statement supplied
+func InstallUpdatedAtTrigger(db *sql.DB) error {
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION records_set_updated_at() RETURNS trigger AS $$
+		BEGIN
+			NEW.updated_at := EXTRACT(EPOCH FROM NOW())::BIGINT;
+			RETURN NEW;
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS records_updated_at ON records",
+		`CREATE TRIGGER records_updated_at
+		BEFORE UPDATE ON records
+		FOR EACH ROW EXECUTE FUNCTION records_set_updated_at()`,
+	}
+	return execAll(db, queries)
+}
+
+// CreateAuditTable creates the records_audit table holding the before and
+// after image of every change
+func CreateAuditTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS records_audit (
+		id BIGSERIAL PRIMARY KEY,
+		record_id BIGINT NOT NULL,
+		operation VARCHAR(16) NOT NULL,
+		old_data JSONB,
+		new_data JSONB,
+		changed_at BIGINT NOT NULL     -- Unix timestamp
+	);
+	CREATE INDEX IF NOT EXISTS records_audit_record_id ON records_audit (record_id);
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// InstallAuditTrigger creates records_audit if needed and writes a row to
+// it for every INSERT, UPDATE and DELETE on records
+func InstallAuditTrigger(db *sql.DB) error {
+	if err := CreateAuditTable(db); err != nil {
+		return err
+	}
+
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION records_write_audit() RETURNS trigger AS $$
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				INSERT INTO records_audit (record_id, operation, old_data, changed_at)
+				VALUES (OLD.id, TG_OP, to_jsonb(OLD), EXTRACT(EPOCH FROM NOW())::BIGINT);
+			ELSIF TG_OP = 'UPDATE' THEN
+				INSERT INTO records_audit (record_id, operation, old_data, new_data, changed_at)
+				VALUES (NEW.id, TG_OP, to_jsonb(OLD), to_jsonb(NEW), EXTRACT(EPOCH FROM NOW())::BIGINT);
+			ELSE
+				INSERT INTO records_audit (record_id, operation, new_data, changed_at)
+				VALUES (NEW.id, TG_OP, to_jsonb(NEW), EXTRACT(EPOCH FROM NOW())::BIGINT);
+			END IF;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS records_audit ON records",
+		`CREATE TRIGGER records_audit
+		AFTER INSERT OR UPDATE OR DELETE ON records
+		FOR EACH ROW EXECUTE FUNCTION records_write_audit()`,
+	}
+	return execAll(db, queries)
+}
+
+// InstallNotifyTrigger sends {"op": ..., "id": ...} on the given channel
+// (ChangeChannel if empty) after every change to records. The channel is
+// passed to the trigger as a quoted literal, so any name is safe.
+func InstallNotifyTrigger(db *sql.DB, channel string) error {
+	if channel == "" {
+		channel = ChangeChannel
+	}
+
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION records_notify_change() RETURNS trigger AS $$
+		DECLARE
+			record_id BIGINT;
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				record_id := OLD.id;
+			ELSE
+				record_id := NEW.id;
+			END IF;
+			PERFORM pg_notify(TG_ARGV[0], json_build_object('op', TG_OP, 'id', record_id)::text);
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS records_notify ON records",
+		fmt.Sprintf(`CREATE TRIGGER records_notify
+		AFTER INSERT OR UPDATE OR DELETE ON records
+		FOR EACH ROW EXECUTE FUNCTION records_notify_change(%s)`, pq.QuoteLiteral(channel)),
+	}
+	return execAll(db, queries)
+}
+
+// DropTriggers removes every trigger installed by InstallTriggers
+func DropTriggers(db *sql.DB) error {
+	queries := []string{
+		"DROP TRIGGER IF EXISTS records_updated_at ON records",
+		"DROP TRIGGER IF EXISTS records_audit ON records",
+		"DROP TRIGGER IF EXISTS records_notify ON records",
+	}
+	return execAll(db, queries)
+}
+
+// execAll runs the queries in order, stopping at the first error
+func execAll(db *sql.DB, queries []string) error {
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
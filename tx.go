@@ -0,0 +1,105 @@
+// db/tx.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// serializationFailure is the SQLSTATE PostgreSQL returns when a
+// SERIALIZABLE transaction must be retried
+const serializationFailure = "40001"
+
+// TxOptions configures a transaction started by WithTx
+type TxOptions struct {
+	Isolation sql.IsolationLevel
+	ReadOnly  bool
+	// Deferrable lets a SERIALIZABLE READ ONLY transaction wait for a safe
+	// snapshot instead of risking a serialization failure
+	Deferrable bool
+	// MaxRetries is how many times a transaction failing with a
+	// serialization error is retried; defaults to 3, negative disables
+	MaxRetries int
+}
+
+// WithTx runs fn in a transaction, committing if it returns nil and
+// rolling back otherwise. Transactions that fail with a serialization
+// error (SQLSTATE 40001) are retried, so fn must be safe to run again.
+// A nil opts uses the database defaults.
+func WithTx(ctx context.Context, db *sql.DB, opts *TxOptions, fn func(tx *sql.Tx) error) error {
+	if opts == nil {
+		opts = &TxOptions{}
+	}
+	retries := opts.MaxRetries
+	if retries == 0 {
+		retries = 3
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := runTx(ctx, db, opts, fn)
+		if err == nil || attempt >= retries || !isSerializationFailure(err) {
+			return err
+		}
+
+		// Give the conflicting transaction a moment to finish
+		timer := time.NewTimer(time.Duration(attempt+1) * 10 * time.Millisecond)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// runTx makes a single attempt at running fn in a transaction
+func runTx(ctx context.Context, db *sql.DB, opts *TxOptions, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{
+		Isolation: opts.Isolation,
+		ReadOnly:  opts.ReadOnly,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	// database/sql has no notion of DEFERRABLE, so set it directly
+	if opts.Deferrable {
+		if _, err := tx.ExecContext(ctx, "SET TRANSACTION DEFERRABLE"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isSerializationFailure reports whether err means the transaction
+// conflicted with a concurrent one and can be retried
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == serializationFailure
+}
+
+// WithReadTx runs fn inside a READ ONLY, REPEATABLE READ transaction, so
+// every query fn makes sees the same snapshot (e.g. a count and the page
+// it describes, or a multi-table export)
+func WithReadTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	return WithTx(ctx, db, &TxOptions{
+		Isolation: sql.LevelRepeatableRead,
+		ReadOnly:  true,
+	}, fn)
+}
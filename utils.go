@@ -2,8 +2,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"os/user"
 	"time"
 )
 
@@ -15,26 +19,78 @@ type QueryOptions struct {
 	Order  string // "ASC" or "DESC"
 }
 
+// healthCheckQuery is a trivial query proving the database answers
+var healthCheckQuery = RegisterQuery("db.health_check", "SELECT NOW()")
+
 // HealthCheck checks database connectivity and returns status
 func HealthCheck(db *sql.DB) error {
 	var now time.Time
-	err := db.QueryRow("SELECT NOW()").Scan(&now)
+	err := healthCheckQuery.QueryRow(db).Scan(&now)
 	if err != nil {
 		return fmt.Errorf("database health check failed: %v", err)
 	}
 	return nil
 }
 
+// Backoff controls the delay between connection attempts
+type Backoff struct {
+	Initial    time.Duration // first delay, defaults to 100ms
+	Max        time.Duration // upper bound for a single delay, defaults to 5s
+	Multiplier float64       // growth factor per attempt, defaults to 2
+}
+
+// WaitForDB pings the database until it responds or ctx is done, sleeping
+// with exponential backoff between attempts. Use it at startup when the
+// database may come up after the service (Compose, Kubernetes).
+func WaitForDB(ctx context.Context, db *sql.DB, backoff Backoff) error {
+	if backoff.Initial <= 0 {
+		backoff.Initial = 100 * time.Millisecond
+	}
+	if backoff.Max <= 0 {
+		backoff.Max = 5 * time.Second
+	}
+	if backoff.Multiplier < 1 {
+		backoff.Multiplier = 2
+	}
+
+	delay := backoff.Initial
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("database not reachable after %d attempts (%v): %w", attempt, err, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay = time.Duration(float64(delay) * backoff.Multiplier)
+		if delay > backoff.Max {
+			delay = backoff.Max
+		}
+	}
+}
+
+// countRecordsQuery counts all records
+var countRecordsQuery = RegisterQuery("records.count", "SELECT COUNT(*) FROM records")
+
 // GetRecordCount returns total number of records
 func GetRecordCount(db *sql.DB) (int64, error) {
 	var count int64
-	err := db.QueryRow("SELECT COUNT(*) FROM records").Scan(&count)
+	err := countRecordsQuery.QueryRow(db).Scan(&count)
 	return count, err
 }
 
+// deleteRecordQuery deletes a record by ID
+var deleteRecordQuery = RegisterQuery("records.delete", "DELETE FROM records WHERE id = $1")
+
 // DeleteRecord deletes a record by ID
 func DeleteRecord(db *sql.DB, id int64) error {
-	result, err := db.Exec("DELETE FROM records WHERE id = $1", id)
+	result, err := deleteRecordQuery.Exec(db, id)
 	if err != nil {
 		return err
 	}
@@ -51,25 +107,24 @@ func DeleteRecord(db *sql.DB, id int64) error {
 	return nil
 }
 
+// updateRecordQuery updates the mutable columns of a record
+var updateRecordQuery = MustRegisterNamed("records.update", `
+	UPDATE records 
+	SET name = :name, 
+		description = :description, 
+		amount = :amount, 
+		is_active = :is_active, 
+		updated_at = :updated_at
+	WHERE id = :id`)
+
 // UpdateRecord updates a record
 func UpdateRecord(db *sql.DB, record *Record) error {
-	query := `
-	UPDATE records 
-	SET name = $1, 
-		description = $2, 
-		amount = $3, 
-		is_active = $4, 
-		updated_at = $5
-	WHERE id = $6`
-
-	result, err := db.Exec(query,
-		record.Name,
-		record.Description,
-		record.Amount,
-		record.IsActive,
-		record.UpdatedAtUnix,
-		record.ID,
-	)
+	args, err := updateRecordQuery.BindArgs(record)
+	if err != nil {
+		return err
+	}
+
+	result, err := updateRecordQuery.Exec(db, args...)
 	if err != nil {
 		return err
 	}
@@ -99,14 +154,15 @@ func GetRecords(db *sql.DB, opts QueryOptions) ([]*Record, error) {
 		opts.Order = "DESC"
 	}
 
-	query := fmt.Sprintf(`
+	// The sort order is dynamic, so this query is named but not registered
+	query := NamedQuery{Name: "records.list", SQL: fmt.Sprintf(`
 		SELECT id, name, description, amount, is_active, created_at, updated_at
 		FROM records
 		ORDER BY %s %s
 		LIMIT $1 OFFSET $2
-	`, opts.SortBy, opts.Order)
+	`, opts.SortBy, opts.Order)}
 
-	rows, err := db.Query(query, opts.Limit, opts.Offset)
+	rows, err := query.Query(db, opts.Limit, opts.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -133,18 +189,20 @@ func GetRecords(db *sql.DB, opts QueryOptions) ([]*Record, error) {
 	return records, rows.Err()
 }
 
-// SearchRecords searches records by name or description
-func SearchRecords(db *sql.DB, searchTerm string, opts QueryOptions) ([]*Record, error) {
-	query := `
+// searchRecordsQuery matches records by name or description
+var searchRecordsQuery = RegisterQuery("records.search", `
 		SELECT id, name, description, amount, is_active, created_at, updated_at
 		FROM records
 		WHERE name ILIKE $1 OR description ILIKE $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
-	`
+	`)
+
+// SearchRecords searches records by name or description
+func SearchRecords(db *sql.DB, searchTerm string, opts QueryOptions) ([]*Record, error) {
 	searchPattern := "%" + searchTerm + "%"
 
-	rows, err := db.Query(query, searchPattern, opts.Limit, opts.Offset)
+	rows, err := searchRecordsQuery.Query(db, searchPattern, opts.Limit, opts.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -171,10 +229,93 @@ func SearchRecords(db *sql.DB, searchTerm string, opts QueryOptions) ([]*Record,
 	return records, rows.Err()
 }
 
-// TruncateTable removes all records from the table
-func TruncateTable(db *sql.DB) error {
-	_, err := db.Exec("TRUNCATE TABLE records RESTART IDENTITY")
-	return err
+// countSearchQuery counts the records SearchRecords would match
+var countSearchQuery = RegisterQuery("records.search_count",
+	"SELECT COUNT(*) FROM records WHERE name ILIKE $1 OR description ILIKE $1")
+
+// CountSearchRecords returns how many records match searchTerm, ignoring
+// pagination
+func CountSearchRecords(db *sql.DB, searchTerm string) (int64, error) {
+	var count int64
+	err := countSearchQuery.QueryRow(db, "%"+searchTerm+"%").Scan(&count)
+	return count, err
+}
+
+// truncateQuery empties the records table and resets its sequence
+var truncateQuery = RegisterQuery("records.truncate", "TRUNCATE TABLE records RESTART IDENTITY")
+
+// ErrTruncateNotConfirmed is returned when TruncateTable is called
+// without naming the table in TruncateOptions.Confirm
+var ErrTruncateNotConfirmed = errors.New(`truncate not confirmed: set TruncateOptions.Confirm to "records"`)
+
+// TruncateOptions guards TruncateTable against accidental use
+type TruncateOptions struct {
+	// Confirm must be set to "records" for the truncate to run
+	Confirm string
+	// AllowedEnvs restricts truncation to these values of the APP_ENV
+	// environment variable (e.g. "dev", "test"). Empty allows any.
+	AllowedEnvs []string
+	// Actor and Reason are written to records_audit; Actor defaults to
+	// the current OS user
+	Actor  string
+	Reason string
+}
+
+// TruncateTable removes all records from the table once the options
+// confirm the intent, and records who did it in records_audit
+func TruncateTable(db *sql.DB, opts TruncateOptions) error {
+	if opts.Confirm != "records" {
+		return ErrTruncateNotConfirmed
+	}
+
+	if len(opts.AllowedEnvs) > 0 {
+		env := os.Getenv("APP_ENV")
+		allowed := false
+		for _, e := range opts.AllowedEnvs {
+			if e == env {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("truncate not allowed in environment %q", env)
+		}
+	}
+
+	if opts.Actor == "" {
+		opts.Actor = "unknown"
+		if u, err := user.Current(); err == nil {
+			opts.Actor = u.Username
+		}
+	}
+
+	if err := CreateAuditTable(db); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Count and audit in the same transaction so the entry exists
+	// only if the truncate commits
+	_, err = tx.Exec(`
+		INSERT INTO records_audit (record_id, operation, new_data, changed_at)
+		SELECT 0, 'TRUNCATE', jsonb_build_object('actor', $1::TEXT, 'reason', $2::TEXT, 'rows', COUNT(*)), $3
+		FROM records`,
+		opts.Actor, opts.Reason, time.Now().Unix(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := truncateQuery.ExecTx(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // CreateIndex creates an index on specified columns
@@ -0,0 +1,117 @@
+// db/utils_test.go
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeConnector hands out connections whose pings fail until failures
+// have been used up and whose queries block until their context is done
+type fakeConnector struct {
+	failures int32
+	pings    atomic.Int32
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c: c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type fakeConn struct{ c *fakeConnector }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	if c.c.pings.Add(1) <= c.c.failures {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestWaitForDBRetriesUntilReachable(t *testing.T) {
+	c := &fakeConnector{failures: 3}
+	db := sql.OpenDB(c)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := WaitForDB(ctx, db, Backoff{Initial: time.Millisecond}); err != nil {
+		t.Fatalf("WaitForDB: %v", err)
+	}
+	if got := c.pings.Load(); got <= c.failures {
+		t.Errorf("pinged %d times, want more than %d", got, c.failures)
+	}
+}
+
+func TestWaitForDBStopsWhenContextExpires(t *testing.T) {
+	db := sql.OpenDB(&fakeConnector{failures: 1 << 30})
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := WaitForDB(ctx, db, Backoff{Initial: 5 * time.Millisecond, Max: 10 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitForDB error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WaitForDB returned after %s, long past the deadline", elapsed)
+	}
+}
+
+func TestWithTxPropagatesQueryTimeout(t *testing.T) {
+	db := sql.OpenDB(&fakeConnector{})
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT pg_sleep(10)")
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WithTx error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestWithTxStopsOnCancel(t *testing.T) {
+	db := sql.OpenDB(&fakeConnector{})
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err := WithTx(ctx, db, nil, func(tx *sql.Tx) error {
+		_, err := tx.QueryContext(ctx, "SELECT 1")
+		return err
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("WithTx error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,42 @@
+// db/uuid.go
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+)
+
+// UUIDRecord is a record keyed by a UUID instead of a BIGSERIAL
+type UUIDRecord = KeyedRecord[string]
+
+// CreateUUIDTable creates the records_uuid table with UUID primary keys.
+// gen_random_uuid() is built in from PostgreSQL 13 (pgcrypto before that).
+func CreateUUIDTable(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS records_uuid (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		amount NUMERIC(15,2),
+		is_active BOOLEAN,
+		created_at BIGINT NOT NULL,    -- Unix timestamp
+		updated_at BIGINT              -- Nullable Unix timestamp
+	);
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// NewUUID generates a random (version 4) UUID on the client side, for
+// callers that need the key before the insert is committed
+func NewUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
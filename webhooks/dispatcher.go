@@ -0,0 +1,278 @@
+// db/webhooks/dispatcher.go
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"your/path/to/httpdbg"
+)
+
+// Dispatcher delivers queued webhooks. Several dispatchers can run
+// against the same database; each delivery is claimed by only one.
+type Dispatcher struct {
+	DB *sql.DB
+	// Client sends the requests; defaults to httpdbg.NewClient() with
+	// retries and a 10 second timeout, and with the signature header
+	// redacted. Keep its Timeout well under the 60 second lease.
+	Client *http.Client
+	// DebugOutput receives the default client's debug log instead of
+	// os.Stdout; it has no effect when Client is set
+	DebugOutput io.Writer
+	// MaxAttempts before a delivery is marked failed; defaults to 8
+	MaxAttempts int
+	// BatchSize is how many deliveries are claimed per poll; defaults to 20
+	BatchSize int
+	// PollInterval between queue checks when idle; defaults to 2 seconds
+	PollInterval time.Duration
+	// RetryBase is the first retry delay, doubled on every attempt;
+	// defaults to 10 seconds
+	RetryBase time.Duration
+
+	clientOnce    sync.Once
+	defaultClient *http.Client
+}
+
+// claimLease is how long a claimed delivery stays hidden from other
+// dispatchers while it's being sent. It is renewed right before each send,
+// so it only has to cover one request, not the whole batch.
+const claimLease = 60
+
+// client returns Client, or the debug client built once on first use
+func (d *Dispatcher) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	d.clientOnce.Do(func() {
+		opts := []httpdbg.Option{
+			// The timeout covers every retry, so one send stays well
+			// inside the lease
+			httpdbg.WithTimeout(10 * time.Second),
+			httpdbg.WithRetry(3),
+			httpdbg.WithRedaction(httpdbg.Redaction{Headers: []string{SignatureHeader}}),
+		}
+		if d.DebugOutput != nil {
+			opts = append(opts, httpdbg.WithWriter(d.DebugOutput))
+		}
+		d.defaultClient = httpdbg.NewClient(opts...)
+	})
+	return d.defaultClient
+}
+
+// Run delivers webhooks until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) error {
+	interval := d.PollInterval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	for {
+		n, err := d.DeliverDue(ctx)
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+		}
+		if n > 0 && err == nil {
+			continue
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// DeliverDue claims one batch of due deliveries, sends them and records
+// the outcome. It returns how many deliveries were attempted.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	due, err := d.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for _, delivery := range due {
+		held, err := d.renew(ctx, delivery)
+		if err != nil {
+			return attempted, err
+		}
+		if !held {
+			// The lease ran out and another dispatcher took it over
+			continue
+		}
+		d.deliver(ctx, delivery)
+		attempted++
+	}
+	return attempted, nil
+}
+
+// renew pushes a claimed delivery's lease out again before it is sent.
+// It reports false if the delivery was claimed again in the meantime,
+// which bumps its attempt count.
+func (d *Dispatcher) renew(ctx context.Context, delivery dueDelivery) (bool, error) {
+	result, err := d.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id = $1 AND status = 'pending' AND attempts = $3`,
+		delivery.ID, time.Now().Unix()+claimLease, delivery.Attempts,
+	)
+	if err != nil {
+		return false, fmt.Errorf("renewing lease on delivery %d: %v", delivery.ID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// dueDelivery is a claimed delivery along with its endpoint secret
+type dueDelivery struct {
+	*Delivery
+	secret string
+}
+
+// claim locks due deliveries and pushes their next attempt past the
+// lease, so a crashed dispatcher's work is picked up again later
+func (d *Dispatcher) claim(ctx context.Context) ([]dueDelivery, error) {
+	batch := d.BatchSize
+	if batch <= 0 {
+		batch = 20
+	}
+	now := time.Now().Unix()
+
+	tx, err := d.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = $2
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id AND d.id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns+`, e.secret`,
+		now, now+claimLease, batch,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueDelivery
+	for rows.Next() {
+		var secret string
+		delivery, err := scanDelivery(func(dest ...interface{}) error {
+			return rows.Scan(append(dest, &secret)...)
+		})
+		if err != nil {
+			return nil, err
+		}
+		due = append(due, dueDelivery{Delivery: delivery, secret: secret})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return due, tx.Commit()
+}
+
+// deliver sends one delivery and stores the result
+func (d *Dispatcher) deliver(ctx context.Context, delivery dueDelivery) {
+	statusCode, err := d.send(ctx, d.client(), delivery)
+	now := time.Now().Unix()
+
+	if err == nil {
+		_, err = d.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'delivered', delivered_at = $2, last_status_code = $3, last_error = NULL
+			WHERE id = $1`,
+			delivery.ID, now, statusCode,
+		)
+		if err != nil {
+			log.Printf("webhooks: recording delivery %d: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	maxAttempts := d.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 8
+	}
+	base := d.RetryBase
+	if base <= 0 {
+		base = 10 * time.Second
+	}
+
+	shift := delivery.Attempts - 1
+	if shift > 16 {
+		shift = 16
+	}
+	status := StatusPending
+	next := now + int64((base << uint(shift)).Seconds())
+	if delivery.Attempts >= maxAttempts {
+		status = StatusFailed
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	_, dbErr := d.DB.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, next_attempt_at = $3, last_status_code = $4, last_error = $5
+		WHERE id = $1`,
+		delivery.ID, status, next, code, err.Error(),
+	)
+	if dbErr != nil {
+		log.Printf("webhooks: recording failure of delivery %d: %v", delivery.ID, dbErr)
+	}
+}
+
+// send POSTs the signed payload; any non-2xx response is a failure
+func (d *Dispatcher) send(ctx context.Context, client *http.Client, delivery dueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.secret, timestamp, delivery.Payload))
+	// Resending the same delivery is safe, and the key lets the retrying
+	// client repeat the POST
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(delivery.ID, 10))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,51 @@
+// db/webhooks/signature.go
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on every delivery
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Sign computes the signature header value for a payload:
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body))
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature headers of a received webhook. Deliveries
+// older than tolerance are rejected to limit replays; zero disables the check.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("missing or invalid %s header", TimestampHeader)
+	}
+
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("webhook timestamp outside tolerance (%v)", age)
+		}
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SignatureHeader))) {
+		return fmt.Errorf("webhook signature mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+// db/webhooks/webhooks.go
+package webhooks
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Delivery states
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Delivery is a single webhook delivery to a single endpoint
+type Delivery struct {
+	ID             int64
+	EndpointID     int64
+	URL            string
+	Event          string
+	RecordID       int64
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  int64 // Unix timestamp
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      int64  // Unix timestamp
+	DeliveredAt    *int64 // Nullable Unix timestamp
+}
+
+// Execer is satisfied by both *sql.DB and *sql.Tx, so deliveries can be
+// enqueued in the same transaction as the change they describe
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateTables creates the webhook_endpoints and webhook_deliveries tables
+func CreateTables(db *sql.DB) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS webhook_endpoints (
+		id BIGSERIAL PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret TEXT NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT TRUE,
+		created_at BIGINT NOT NULL     -- Unix timestamp
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id BIGSERIAL PRIMARY KEY,
+		endpoint_id BIGINT NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+		event VARCHAR(64) NOT NULL,
+		record_id BIGINT NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(16) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at BIGINT NOT NULL, -- Unix timestamp
+		last_status_code INTEGER,
+		last_error TEXT,
+		created_at BIGINT NOT NULL,      -- Unix timestamp
+		delivered_at BIGINT              -- Nullable Unix timestamp
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_due
+		ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+	`
+	_, err := db.Exec(query)
+	return err
+}
+
+// AddEndpoint registers a URL to receive record events, signed with secret
+func AddEndpoint(db *sql.DB, url, secret string) (int64, error) {
+	var id int64
+	err := db.QueryRow(
+		"INSERT INTO webhook_endpoints (url, secret, created_at) VALUES ($1, $2, $3) RETURNING id",
+		url, secret, time.Now().Unix(),
+	).Scan(&id)
+	return id, err
+}
+
+// DisableEndpoint stops new deliveries to an endpoint
+func DisableEndpoint(db *sql.DB, id int64) error {
+	_, err := db.Exec("UPDATE webhook_endpoints SET active = FALSE WHERE id = $1", id)
+	return err
+}
+
+// Enqueue queues an event for every active endpoint and returns the
+// number of deliveries created
+func Enqueue(q Execer, event string, recordID int64, payload interface{}) (int64, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	result, err := q.Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event, record_id, payload, next_attempt_at, created_at)
+		SELECT id, $1, $2, $3, $4, $4 FROM webhook_endpoints WHERE active`,
+		event, recordID, body, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// InstallRecordTrigger makes every INSERT, UPDATE and DELETE on records
+// enqueue a delivery (event "record.insert", "record.update" or
+// "record.delete") for each active endpoint, with the row as payload
+func InstallRecordTrigger(db *sql.DB) error {
+	queries := []string{
+		`CREATE OR REPLACE FUNCTION records_enqueue_webhooks() RETURNS trigger AS $$
+		DECLARE
+			row_data JSONB;
+			row_id BIGINT;
+			now_unix BIGINT := EXTRACT(EPOCH FROM NOW())::BIGINT;
+		BEGIN
+			IF TG_OP = 'DELETE' THEN
+				row_data := to_jsonb(OLD);
+				row_id := OLD.id;
+			ELSE
+				row_data := to_jsonb(NEW);
+				row_id := NEW.id;
+			END IF;
+			INSERT INTO webhook_deliveries (endpoint_id, event, record_id, payload, next_attempt_at, created_at)
+			SELECT id, 'record.' || lower(TG_OP), row_id, row_data, now_unix, now_unix
+			FROM webhook_endpoints WHERE active;
+			RETURN NULL;
+		END;
+		$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS records_webhooks ON records",
+		`CREATE TRIGGER records_webhooks
+		AFTER INSERT OR UPDATE OR DELETE ON records
+		FOR EACH ROW EXECUTE FUNCTION records_enqueue_webhooks()`,
+	}
+
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliveryColumns is the column list scanned by scanDelivery
+const deliveryColumns = `
+	d.id, d.endpoint_id, e.url, d.event, d.record_id, d.payload, d.status,
+	d.attempts, d.next_attempt_at, d.last_status_code, d.last_error,
+	d.created_at, d.delivered_at`
+
+// scanDelivery scans a row selected with deliveryColumns
+func scanDelivery(scan func(dest ...interface{}) error) (*Delivery, error) {
+	d := &Delivery{}
+	var payload []byte
+	err := scan(
+		&d.ID,
+		&d.EndpointID,
+		&d.URL,
+		&d.Event,
+		&d.RecordID,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	d.Payload = payload
+	return d, nil
+}
+
+// GetDelivery returns the current state of a delivery
+func GetDelivery(db *sql.DB, id int64) (*Delivery, error) {
+	row := db.QueryRow(`SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.id = $1`, id)
+	return scanDelivery(row.Scan)
+}
+
+// ListDeliveries returns deliveries with the given status (all when
+// empty), newest first
+func ListDeliveries(db *sql.DB, status string, limit int) ([]*Delivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	rows, err := db.Query(`SELECT `+deliveryColumns+`
+		FROM webhook_deliveries d JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE $1 = '' OR d.status = $1
+		ORDER BY d.id DESC
+		LIMIT $2`, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RetryDelivery puts a failed delivery back in the queue for immediate
+// delivery, with a fresh attempt budget
+func RetryDelivery(db *sql.DB, id int64) error {
+	_, err := db.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $2
+		WHERE id = $1 AND status = 'failed'`,
+		id, time.Now().Unix(),
+	)
+	return err
+}